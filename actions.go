@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// localAction is a command gomu implements itself on top of the dependency chain
+type localAction func(options gomu.Options) error
+
+// localActions are run instead of handing options to mod-utils
+var localActions = map[string]localAction{
+	"list":           printList,
+	"graph":          printGraph,
+	"why":            printWhy,
+	"outdated":       printOutdated,
+	"drift":          printDrift,
+	"check-cycles":   printCheckCycles,
+	"status":         printStatus,
+	"log":            printLog,
+	"diff":           printDiff,
+	"exec":           runExec,
+	"checkout":       runCheckout,
+	"stash":          runStash,
+	"unstash":        runUnstash,
+	"snapshot":       printSnapshot,
+	"restore":        runRestore,
+	"clone":          runClone,
+	"work":           writeWork,
+	"prune-branches": runPruneBranches,
+}
+
+// runLocalAction runs options.Action if gomu implements it, then exits
+func runLocalAction(options gomu.Options) {
+	action, ok := localActions[options.Action]
+	if !ok {
+		return
+	}
+
+	if err := action(options); err != nil {
+		exitWithError(err.Error())
+	}
+
+	removeScope()
+	os.Exit(exitSuccess)
+}
+
+// printList prints each library in the chain along with its depth
+// Cycles are reported first, since the order of their libraries is arbitrary
+// Output is the same with or without -parallel
+func printList(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	cycles := findCycles(chain, options.DirectImport)
+
+	var report *jsonReport
+	if outputJSON {
+		report = newJSONReport(options)
+
+		encoder := json.NewEncoder(jsonOut)
+		for _, c := range cycles {
+			encoder.Encode(c)
+		}
+	} else if len(cycles) > 0 {
+		printCycles(cycles)
+	}
+
+	results := runLevels(chain, parallel, func(lib *library, w io.Writer) error {
+		printListEntry(w, lib, options)
+		return nil
+	})
+
+	if report != nil {
+		finishParallelJSON(report, results, resultErrors(results))
+	}
+
+	return
+}
+
+// printListEntry prints a library as `depth name (module)`, or just its name with -name-only
+func printListEntry(w io.Writer, lib *library, options gomu.Options) {
+	if options.LogLevel == com.NAMEONLY {
+		fmt.Fprintln(w, lib.Name)
+		return
+	}
+
+	fmt.Fprintf(w, "%d %s (%s)\n", lib.Depth, lib.Name, lib.Module)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/hatchify/simply"
+)
+
+func TestList_Entry(context *testing.T) {
+	lib := &library{Name: "scribe", Module: "github.com/hatchify/scribe", Depth: 1}
+
+	var buf bytes.Buffer
+	printListEntry(&buf, lib, gomu.Options{LogLevel: com.NORMAL})
+
+	test := simply.Target(buf.String(), context, "List should always print the depth")
+	result := test.Equals("1 scribe (github.com/hatchify/scribe)\n")
+	test.Validate(result)
+
+	buf.Reset()
+	printListEntry(&buf, lib, gomu.Options{LogLevel: com.NAMEONLY})
+
+	test = simply.Target(buf.String(), context, "List should only print the name with -name-only")
+	result = test.Equals("scribe\n")
+	test.Validate(result)
+}
+
+func TestList_Cycles(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	// parg now requires mod-utils, which requires parg through scribe
+	writeLibrary(context, root, "parg", "module github.com/hatchify/parg\n\nrequire github.com/gomuserver/mod-utils v0.0.6\n")
+
+	records, err := ioutil.TempFile("", "gomu-list")
+	if err != nil {
+		context.Fatal(err)
+	}
+	defer os.Remove(records.Name())
+
+	// Records go to jsonOut, the human readable output is dropped
+	stdout := os.Stdout
+	os.Stdout, outputJSON, jsonOut = nil, true, records
+	defer func() { os.Stdout, outputJSON, jsonOut = stdout, false, stdout }()
+
+	err = printList(gomu.Options{Action: "list", TargetDirectories: []string{root}})
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	records.Seek(0, 0)
+
+	var first cycle
+	json.NewDecoder(records).Decode(&first)
+
+	test = simply.Target(first.Libraries, context, "List should report the cycle before the libraries")
+	result = test.Equals([]string{"mod-utils", "parg", "scribe"})
+	test.Validate(result)
+}
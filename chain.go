@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// library is a local working copy found in one of the target directories
+type library struct {
+	Name   string
+	Dir    string
+	Module string
+	// GoVersion is the go directive of go.mod
+	GoVersion string
+
+	// Requires holds the require directives of go.mod
+	Requires []requirement
+	// Replaces holds the replace directives of go.mod
+	Replaces []replacement
+	// Sums holds every module path referenced by go.sum
+	Sums []string
+
+	// Level is the position of the library within the sorted chain
+	// Libraries without local dependencies in the chain are at level 0
+	Level int
+	// Depth is the number of steps from the nearest selected library
+	// Selected libraries have a depth of 0
+	Depth int
+	// Deps holds the local libraries this library depends on
+	Deps []*library
+}
+
+// requirement is a single require directive from a go.mod file
+type requirement struct {
+	Module   string
+	Version  string
+	Indirect bool
+	Line     int
+}
+
+// replacement is a single replace directive from a go.mod file
+type replacement struct {
+	Module        string
+	Version       string
+	Target        string
+	TargetVersion string
+	Line          int
+}
+
+// Requirement returns the require directive for the provided module, if any
+func (lib *library) Requirement(module string) (req requirement, ok bool) {
+	for _, req = range lib.Requires {
+		if req.Module == module {
+			return req, true
+		}
+	}
+
+	return requirement{}, false
+}
+
+// Replacement returns the replace directive for the provided module, if any
+func (lib *library) Replacement(module string) (rep replacement, ok bool) {
+	for _, rep = range lib.Replaces {
+		if rep.Module == module {
+			return rep, true
+		}
+	}
+
+	return replacement{}, false
+}
+
+// Matches returns true if the filter names this library by directory, module path or pattern
+func (lib *library) Matches(filter string) bool {
+	return matchPattern(filter, lib)
+}
+
+// libraryFromDir parses the go.mod (and go.sum) within dir
+func libraryFromDir(dir string) (lib *library, err error) {
+	lib = &library{Name: filepath.Base(dir), Dir: dir}
+	if err = lib.readModFile(filepath.Join(dir, "go.mod")); err != nil {
+		return nil, err
+	}
+
+	// go.sum is optional
+	lib.readSumFile(filepath.Join(dir, "go.sum"))
+	return
+}
+
+func (lib *library) readModFile(filename string) (err error) {
+	var f *os.File
+	if f, err = os.Open(filename); err != nil {
+		return
+	}
+	defer f.Close()
+
+	return lib.readMod(f)
+}
+
+// readMod parses go.mod directives from r
+func (lib *library) readMod(r io.Reader) (err error) {
+	var (
+		block  string
+		lineNo int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		line, comment := splitComment(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(block) > 0 {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+
+			lib.addDirective(block, fields, comment, lineNo)
+			continue
+		}
+
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+
+		lib.addDirective(fields[0], fields[1:], comment, lineNo)
+	}
+
+	return scanner.Err()
+}
+
+func (lib *library) addDirective(verb string, args []string, comment string, lineNo int) {
+	for i := range args {
+		args[i] = strings.Trim(args[i], "\"`")
+	}
+
+	switch verb {
+	case "module":
+		if len(args) > 0 {
+			lib.Module = args[0]
+		}
+	case "go":
+		if len(args) > 0 {
+			lib.GoVersion = args[0]
+		}
+	case "require":
+		if len(args) < 2 {
+			return
+		}
+
+		lib.Requires = append(lib.Requires, requirement{
+			Module:   args[0],
+			Version:  args[1],
+			Indirect: comment == "indirect",
+			Line:     lineNo,
+		})
+	case "replace":
+		var rep replacement
+		rep.Line = lineNo
+
+		// Formats: `old [v] => new [v]`
+		arrow := -1
+		for i, arg := range args {
+			if arg == "=>" {
+				arrow = i
+			}
+		}
+
+		if arrow < 1 || arrow == len(args)-1 {
+			return
+		}
+
+		rep.Module = args[0]
+		if arrow == 2 {
+			rep.Version = args[1]
+		}
+
+		rep.Target = args[arrow+1]
+		if len(args) > arrow+2 {
+			rep.TargetVersion = args[arrow+2]
+		}
+
+		lib.Replaces = append(lib.Replaces, rep)
+	}
+}
+
+func (lib *library) readSumFile(filename string) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || seen[fields[0]] {
+			continue
+		}
+
+		seen[fields[0]] = true
+		lib.Sums = append(lib.Sums, fields[0])
+	}
+}
+
+// splitComment separates a go.mod line from its trailing comment
+func splitComment(line string) (content, comment string) {
+	if i := strings.Index(line, "//"); i >= 0 {
+		comment = strings.TrimSpace(line[i+2:])
+		line = line[:i]
+	}
+
+	content = strings.TrimSpace(line)
+	return
+}
+
+// discoverLibraries returns each working copy with a go.mod within the target directories
+// Excluded directories and libraries (including those in .gomuignore) are skipped
+func discoverLibraries(dirs []string) (libs []*library, err error) {
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if isExcludedDir(dir) {
+			continue
+		}
+
+		var infos []os.FileInfo
+		if infos, err = ioutil.ReadDir(dir); err != nil {
+			return
+		}
+
+		ignored := readIgnoreFile(dir)
+
+		for _, info := range infos {
+			libDir := filepath.Join(dir, info.Name())
+			if info.Mode()&os.ModeSymlink != 0 {
+				// Follow links to working copies, reporting where they really live
+				if target, err := filepath.EvalSymlinks(libDir); err == nil {
+					libDir = target
+					info, _ = os.Stat(target)
+				}
+			}
+
+			if info == nil || !info.IsDir() || strings.HasPrefix(filepath.Base(libDir), ".") {
+				continue
+			}
+
+			if abs, err := filepath.Abs(libDir); err == nil {
+				if seen[abs] {
+					continue
+				}
+
+				seen[abs] = true
+			}
+
+			if _, err := os.Stat(filepath.Join(libDir, "go.mod")); err != nil {
+				continue
+			}
+
+			lib, err := libraryFromDir(libDir)
+			if err != nil || len(lib.Module) == 0 || isExcluded(lib, ignored) {
+				continue
+			}
+
+			libs = append(libs, lib)
+		}
+	}
+
+	return
+}
+
+// selectDependents is set by the -dependents flag
+var selectDependents bool
+
+// maxDepth is set by the -depth flag, negative when unbounded
+var maxDepth = -1
+
+// parseDepth validates the value of the -depth flag
+func parseDepth(value string) (depth int, err error) {
+	if len(value) == 0 {
+		return -1, nil
+	}
+
+	if depth, err = strconv.Atoi(value); err != nil || depth < 0 {
+		return 0, fmt.Errorf("-depth expects zero or a positive number, received %q", value)
+	}
+
+	return
+}
+
+// dependencyChain links local libraries together, selects the ones matching filters
+// (along with their dependencies, or their dependents with -dependents),
+// and returns them sorted so dependencies come first
+func dependencyChain(libs []*library, filters []string, direct bool) (chain []*library) {
+	byModule := make(map[string]*library, len(libs))
+	for _, lib := range libs {
+		byModule[lib.Module] = lib
+	}
+
+	for _, lib := range libs {
+		lib.Deps = lib.Deps[:0]
+		seen := make(map[*library]bool)
+
+		modules := make([]string, 0, len(lib.Requires)+len(lib.Sums))
+		for _, req := range lib.Requires {
+			modules = append(modules, req.Module)
+		}
+
+		if !direct {
+			modules = append(modules, lib.Sums...)
+		}
+
+		for _, module := range modules {
+			dep, ok := byModule[module]
+			if !ok || dep == lib || seen[dep] {
+				continue
+			}
+
+			seen[dep] = true
+			lib.Deps = append(lib.Deps, dep)
+		}
+	}
+
+	// Walk down to dependencies, or up to dependents with -dependents
+	edges := make(map[*library][]*library, len(libs))
+	for _, lib := range libs {
+		if !selectDependents {
+			edges[lib] = lib.Deps
+			continue
+		}
+
+		for _, dep := range lib.Deps {
+			edges[dep] = append(edges[dep], lib)
+		}
+	}
+
+	// Breadth first, so each library is reached by its shortest path
+	depths := make(map[*library]int)
+	var queue []*library
+	for _, lib := range libs {
+		if len(filters) == 0 {
+			depths[lib] = 0
+			continue
+		}
+
+		for _, filter := range filters {
+			if _, ok := depths[lib]; !ok && lib.Matches(filter) {
+				depths[lib] = 0
+				queue = append(queue, lib)
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		lib := queue[0]
+		queue = queue[1:]
+
+		depth := depths[lib]
+		if (direct && depth >= 1) || (maxDepth >= 0 && depth >= maxDepth) {
+			continue
+		}
+
+		for _, next := range edges[lib] {
+			if _, ok := depths[next]; !ok {
+				depths[next] = depth + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	for _, lib := range libs {
+		if depth, ok := depths[lib]; ok {
+			lib.Depth = depth
+			chain = append(chain, lib)
+		}
+	}
+
+	return sortChain(chain)
+}
+
+// sortChain orders libraries by level, then by name
+func sortChain(libs []*library) []*library {
+	inChain := make(map[*library]bool, len(libs))
+	for _, lib := range libs {
+		inChain[lib] = true
+		lib.Level = -1
+	}
+
+	var levelOf func(lib *library, visiting map[*library]bool) int
+	levelOf = func(lib *library, visiting map[*library]bool) int {
+		if lib.Level >= 0 {
+			return lib.Level
+		}
+
+		if visiting[lib] {
+			// Cyclic dependency, stop descending
+			return 0
+		}
+
+		visiting[lib] = true
+		level := 0
+		for _, dep := range lib.Deps {
+			if !inChain[dep] {
+				continue
+			}
+
+			if d := levelOf(dep, visiting) + 1; d > level {
+				level = d
+			}
+		}
+
+		delete(visiting, lib)
+		lib.Level = level
+		return level
+	}
+
+	for _, lib := range libs {
+		levelOf(lib, make(map[*library]bool))
+	}
+
+	sort.SliceStable(libs, func(i, j int) bool {
+		if libs[i].Level != libs[j].Level {
+			return libs[i].Level < libs[j].Level
+		}
+
+		return libs[i].Name < libs[j].Name
+	})
+
+	return libs
+}
+
+// chainLevels groups a sorted chain by level
+// Libraries within a level do not depend on each other
+func chainLevels(chain []*library) (levels [][]*library) {
+	for _, lib := range chain {
+		for len(levels) <= lib.Level {
+			levels = append(levels, nil)
+		}
+
+		levels[lib.Level] = append(levels[lib.Level], lib)
+	}
+
+	return
+}
+
+// loadChain discovers and sorts the libraries selected by options
+func loadChain(dirs, filters []string, direct bool) (chain []*library, err error) {
+	var libs []*library
+	if libs, err = discoverLibraries(dirs); err != nil {
+		return
+	}
+
+	chain = dependencyChain(libs, filters, direct)
+	if len(sinceRef) > 0 {
+		chain = changedSince(chain, sinceRef)
+	}
+
+	return
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func writeLibrary(context *testing.T, root, name, modFile string) {
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		context.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(modFile), 0644); err != nil {
+		context.Fatal(err)
+	}
+}
+
+func testWorkspace(context *testing.T) (root string) {
+	root, err := ioutil.TempDir("", "gomu")
+	if err != nil {
+		context.Fatal(err)
+	}
+
+	writeLibrary(context, root, "parg", "module github.com/hatchify/parg\n\ngo 1.14\n")
+	writeLibrary(context, root, "scribe", "module github.com/hatchify/scribe\n\nrequire github.com/hatchify/parg v0.1.0\n")
+	writeLibrary(context, root, "mod-utils", `module github.com/gomuserver/mod-utils
+
+go 1.14
+
+require (
+	github.com/hatchify/scribe v0.4.84 // indirect
+	golang.org/x/mod v0.3.0
+)
+
+replace github.com/hatchify/parg v0.1.0 => ../parg
+`)
+	writeLibrary(context, root, "unrelated", "module github.com/hatchify/unrelated\n")
+	return
+}
+
+func TestChain_ModFile(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	lib, err := libraryFromDir(filepath.Join(root, "mod-utils"))
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(lib.Module, context, "Module should be github.com/gomuserver/mod-utils")
+	result = test.Equals("github.com/gomuserver/mod-utils")
+	test.Validate(result)
+
+	test = simply.Target(lib.Requires, context, "Requires should include both directives")
+	result = test.Equals([]requirement{
+		{Module: "github.com/hatchify/scribe", Version: "v0.4.84", Indirect: true, Line: 6},
+		{Module: "golang.org/x/mod", Version: "v0.3.0", Line: 7},
+	})
+	test.Validate(result)
+
+	test = simply.Target(lib.Replaces, context, "Replaces should include parg")
+	result = test.Equals([]replacement{
+		{Module: "github.com/hatchify/parg", Version: "v0.1.0", Target: "../parg", Line: 10},
+	})
+	test.Validate(result)
+}
+
+func TestChain_Sorted(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	chain, err := loadChain([]string{root}, []string{"mod-utils"}, false)
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	names := make([]string, len(chain))
+	for i, lib := range chain {
+		names[i] = lib.Name
+	}
+
+	test = simply.Target(names, context, "Chain should be sorted by dependency")
+	result = test.Equals([]string{"parg", "scribe", "mod-utils"})
+	test.Validate(result)
+
+	test = simply.Target(chain[2].Level, context, "mod-utils should be at level 2")
+	result = test.Equals(2)
+	test.Validate(result)
+
+	test = simply.Target(chain[0].Depth, context, "parg should be 2 steps from mod-utils")
+	result = test.Equals(2)
+	test.Validate(result)
+}
+
+func TestChain_Patterns(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	excludePatterns = []string{"unrelated"}
+	defer func() { excludePatterns = nil }()
+
+	chain, _ := loadChain([]string{root}, []string{"/^(parg|scribe)$/"}, false)
+	test := simply.Target(len(chain), context, "Regex should select parg and scribe")
+	result := test.Equals(2)
+	test.Validate(result)
+
+	chain, _ = loadChain([]string{root}, []string{"*"}, false)
+	test = simply.Target(len(chain), context, "Glob should select everything but the excluded library")
+	result = test.Equals(3)
+	test.Validate(result)
+}
+
+func TestChain_Dependents(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	selectDependents = true
+	defer func() { selectDependents = false }()
+
+	chain, _ := loadChain([]string{root}, []string{"scribe"}, false)
+
+	names := make([]string, len(chain))
+	for i, lib := range chain {
+		names[i] = lib.Name
+	}
+
+	test := simply.Target(names, context, "Dependents of scribe should be scribe then mod-utils")
+	result := test.Equals([]string{"scribe", "mod-utils"})
+	test.Validate(result)
+}
+
+func TestChain_MaxDepth(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	maxDepth = 1
+	defer func() { maxDepth = -1 }()
+
+	chain, _ := loadChain([]string{root}, []string{"mod-utils"}, false)
+
+	names := make([]string, len(chain))
+	for i, lib := range chain {
+		names[i] = lib.Name
+	}
+
+	test := simply.Target(names, context, "Depth 1 should stop at scribe")
+	result := test.Equals([]string{"scribe", "mod-utils"})
+	test.Validate(result)
+}
+
+func TestChain_Cycles(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	// parg now requires mod-utils, which requires parg through scribe
+	writeLibrary(context, root, "parg", "module github.com/hatchify/parg\n\nrequire github.com/gomuserver/mod-utils v0.0.6\n")
+
+	chain, _ := loadChain([]string{root}, nil, false)
+	cycles := findCycles(chain, false)
+
+	test := simply.Target(len(cycles), context, "There should be 1 cycle")
+	result := test.Equals(1)
+	test.Validate(result)
+
+	test = simply.Target(cycles[0].Libraries, context, "Cycle should include parg, scribe and mod-utils")
+	result = test.Equals([]string{"mod-utils", "parg", "scribe"})
+	test.Validate(result)
+
+	test = simply.Target(len(cycles[0].Edges), context, "Cycle should have 3 edges")
+	result = test.Equals(3)
+	test.Validate(result)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// defaultBranch is set by the -default-branch flag
+var defaultBranch = "master"
+
+// checkoutRecord reports where a library ended up
+type checkoutRecord struct {
+	Type    string `json:"type"`
+	Library string `json:"library"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	// Result is switched, fallback, unchanged or failed
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// switchBranch checks out branch if it exists locally or on the remote
+// Branches are never created, except to track an existing remote branch
+func switchBranch(lib *library, w io.Writer, branch string) (ok bool, err error) {
+	switch local, remote := branchExists(lib.Dir, branch); {
+	case local:
+		err = runCommand(lib, w, "git", "checkout", branch)
+	case remote:
+		err = runCommand(lib, w, "git", "checkout", "-b", branch, "--track", remoteName+"/"+branch)
+	default:
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// checkoutLibrary fetches the remote, then switches lib to branch, falling back to fallback when branch does not exist
+// With -dry-run nothing is fetched or switched
+func checkoutLibrary(lib *library, w io.Writer, branch, fallback string) (record checkoutRecord) {
+	record = checkoutRecord{Type: "checkout", Library: lib.Name}
+	record.From, _ = gomu.LibraryFromPath(lib.Dir).File.CurrentBranch()
+	record.From = strings.TrimSpace(record.From)
+	record.To = record.From
+
+	var (
+		ok  bool
+		err error
+	)
+
+	// Remote branches are only known once fetched, a dry run relies on the last fetch
+	if url, _ := gitOutput(lib.Dir, "remote", "get-url", remoteName); len(url) > 0 && !dryRun {
+		err = runCommand(lib, w, "git", "fetch", remoteName)
+	}
+
+	// A dry run reports where lib would end up without switching
+	switchTo := func(branch string) (bool, error) {
+		if dryRun {
+			local, remote := branchExists(lib.Dir, branch)
+			return local || remote, nil
+		}
+
+		return switchBranch(lib, w, branch)
+	}
+
+	target := branch
+	if record.From != branch && err == nil {
+		ok, err = switchTo(branch)
+		if !ok && err == nil && len(fallback) > 0 && record.From != fallback {
+			target = fallback
+			ok, err = switchTo(fallback)
+		}
+	}
+
+	switch {
+	case err != nil:
+		record.Result = "failed"
+		record.Error = err.Error()
+	case !ok:
+		record.Result = "unchanged"
+	case target == branch:
+		record.Result = "switched"
+		record.To = target
+	default:
+		record.Result = "fallback"
+		record.To = target
+	}
+
+	return
+}
+
+// runCheckout switches every library in the chain to -branch where it exists
+func runCheckout(options gomu.Options) (err error) {
+	if len(options.Branch) == 0 {
+		return errors.New("checkout requires a branch, usage: `gomu checkout -b feature/Jira-Ticket`")
+	}
+
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	var mux sync.Mutex
+	records := make(map[*library]checkoutRecord, len(chain))
+	results := runLevels(chain, parallel, func(lib *library, w io.Writer) error {
+		record := checkoutLibrary(lib, w, options.Branch, defaultBranch)
+
+		mux.Lock()
+		records[lib] = record
+		mux.Unlock()
+
+		if len(record.Error) > 0 {
+			return errors.New(record.Error)
+		}
+
+		return nil
+	})
+
+	var failures []failure
+	for _, result := range results {
+		if result.err != nil {
+			failures = append(failures, failure{Library: result.lib.Name, Category: categoryGit, Error: result.err.Error()})
+		}
+	}
+
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, lib := range chain {
+			encoder.Encode(records[lib])
+		}
+
+		exitWithFailures(failures)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, lib := range chain {
+		record := records[lib]
+		fmt.Fprintf(w, "%s\t%s\t%s -> %s\t%s\n", record.Library, record.Result, record.From, record.To, record.Error)
+	}
+
+	w.Flush()
+	exitWithFailures(failures)
+	return
+}
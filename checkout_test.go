@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestCheckout_FetchesRemoteBranches(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	// parg.git is the remote, clone is the working copy and other pushes a branch after it was cloned
+	testGit(context, root, "clone", "-q", "--bare", filepath.Join(root, "parg"), "parg.git")
+	testGit(context, root, "clone", "-q", "parg.git", "clone")
+	testGit(context, root, "clone", "-q", "parg.git", "other")
+
+	other := filepath.Join(root, "other")
+	testGit(context, other, "checkout", "-q", "-b", "feature/new")
+	testGit(context, other, "push", "-q", "origin", "feature/new")
+
+	lib := &library{Name: "parg", Dir: filepath.Join(root, "clone")}
+	record := checkoutLibrary(lib, ioutil.Discard, "feature/new", "")
+
+	test := simply.Target(record.Error, context, "Error should not exist")
+	result := test.Equals("")
+	test.Validate(result)
+
+	test = simply.Target(record.Result, context, "A branch pushed after cloning should be found once fetched")
+	result = test.Equals("switched")
+	test.Validate(result)
+
+	test = simply.Target(record.To, context, "Library should end up on feature/new")
+	result = test.Equals("feature/new")
+	test.Validate(result)
+}
+
+func TestCheckout_DryRun(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "parg")
+	from, _ := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	testGit(context, dir, "branch", "feature/new")
+
+	dryRun = true
+	defer func() { dryRun = false }()
+
+	lib := &library{Name: "parg", Dir: dir}
+	record := checkoutLibrary(lib, ioutil.Discard, "feature/new", "")
+
+	test := simply.Target(record.Result, context, "Dry run should report the switch")
+	result := test.Equals("switched")
+	test.Validate(result)
+
+	current, _ := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	test = simply.Target(current, context, "Dry run should stay on the current branch")
+	result = test.Equals(from)
+	test.Validate(result)
+}
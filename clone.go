@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// cloneSSH is set by the -ssh flag
+var cloneSSH bool
+
+// cloneRecord reports what happened to a single module
+type cloneRecord struct {
+	Type   string `json:"type"`
+	Module string `json:"module"`
+	Path   string `json:"path"`
+	// Result is cloned, exists, failed, or skipped for requirements outside the requested organizations
+	Result string `json:"result"`
+	// Depth is 0 for requested modules, and increases for each go.mod hop
+	Depth int    `json:"depth"`
+	Error string `json:"error,omitempty"`
+}
+
+// repoRoot returns the host/org/repo prefix of a module path
+func repoRoot(module string) string {
+	parts := strings.Split(module, "/")
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// orgOf returns the host/org prefix of a module path
+func orgOf(module string) string {
+	if i := strings.LastIndex(repoRoot(module), "/"); i != -1 {
+		return module[:i]
+	}
+
+	return module
+}
+
+// remoteURL returns the url a module is cloned from, using ssh with -ssh
+func remoteURL(module string) string {
+	root := repoRoot(module)
+	if !cloneSSH {
+		return "https://" + root + ".git"
+	}
+
+	parts := strings.SplitN(root, "/", 2)
+	if len(parts) != 2 {
+		return "git@" + root + ".git"
+	}
+
+	return "git@" + parts[0] + ":" + parts[1] + ".git"
+}
+
+// cloneTarget is a module waiting to be cloned
+type cloneTarget struct {
+	module string
+	url    string
+	depth  int
+}
+
+// cloneTargets returns the modules named by args, reading any manifest files
+func cloneTargets(args []string) (targets []cloneTarget, err error) {
+	for _, arg := range args {
+		info, statErr := os.Stat(arg)
+		if statErr != nil || info.IsDir() {
+			targets = append(targets, cloneTarget{module: arg, url: remoteURL(arg)})
+			continue
+		}
+
+		var f *os.File
+		if f, err = os.Open(arg); err != nil {
+			return
+		}
+
+		m, readErr := readManifest(f)
+		f.Close()
+		if readErr != nil {
+			return nil, fmt.Errorf("%s: %v", arg, readErr)
+		}
+
+		for _, entry := range m.Libraries {
+			url := entry.Remote
+			if len(url) == 0 {
+				url = remoteURL(entry.Module)
+			}
+
+			targets = append(targets, cloneTarget{module: entry.Module, url: url})
+		}
+	}
+
+	return
+}
+
+// runClone clones each module into the GOPATH layout under ~/go, then clones missing dependencies
+// Only dependencies within the organizations of the requested modules are followed, up to -depth hops,
+// others are listed as skipped
+func runClone(options gomu.Options) (err error) {
+	if len(options.FilterDependencies) == 0 {
+		return errors.New("clone requires module paths or a manifest, usage: `gomu clone github.com/hatchify/parg`")
+	}
+
+	var queue []cloneTarget
+	if queue, err = cloneTargets(options.FilterDependencies); err != nil {
+		return
+	}
+
+	orgs := make(map[string]bool)
+	for _, target := range queue {
+		orgs[orgOf(target.module)] = true
+	}
+
+	var (
+		records  []cloneRecord
+		failures []failure
+	)
+
+	seen := make(map[string]bool)
+	skipped := make(map[string]bool)
+	for len(queue) > 0 {
+		target := queue[0]
+		queue = queue[1:]
+
+		root := repoRoot(target.module)
+		if seen[root] {
+			continue
+		}
+
+		seen[root] = true
+		lib := &library{Name: filepath.Base(root), Module: target.module, Dir: cloneDir(root)}
+		record := cloneRecord{Type: "clone", Module: target.module, Path: lib.Dir, Result: "exists", Depth: target.depth}
+
+		if _, statErr := os.Stat(lib.Dir); statErr != nil {
+			record.Result = "cloned"
+			if !dryRun {
+				if err := cloneRepo(lib, target.url); err != nil {
+					record.Result = "failed"
+					record.Error = err.Error()
+					failures = append(failures, failure{Library: lib.Name, Category: categoryGit, Error: record.Error})
+				}
+			}
+		}
+
+		records = append(records, record)
+		if record.Result == "failed" || (maxDepth >= 0 && target.depth >= maxDepth) {
+			continue
+		}
+
+		// Follow requirements from go.mod, which may live in a subdirectory of the repository
+		modDir := filepath.Join(lib.Dir, strings.TrimPrefix(strings.TrimPrefix(target.module, root), "/"))
+		parsed, parseErr := libraryFromDir(modDir)
+		if parseErr != nil {
+			if parsed, parseErr = libraryFromDir(lib.Dir); parseErr != nil {
+				continue
+			}
+		}
+
+		for _, req := range parsed.Requires {
+			switch root := repoRoot(req.Module); {
+			case orgs[orgOf(req.Module)]:
+				if !seen[root] {
+					queue = append(queue, cloneTarget{module: req.Module, url: remoteURL(req.Module), depth: target.depth + 1})
+				}
+			case !skipped[root]:
+				skipped[root] = true
+				records = append(records, cloneRecord{Type: "clone", Module: req.Module, Result: "skipped", Depth: target.depth + 1})
+			}
+		}
+	}
+
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, record := range records {
+			encoder.Encode(record)
+		}
+
+		exitWithFailures(failures)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, record := range records {
+		fmt.Fprintf(w, "%s%s\t%s\t%s\n", strings.Repeat("  ", record.Depth), record.Module, record.Result, record.Path)
+	}
+
+	w.Flush()
+	if len(failures) > 0 {
+		printFailures(failures)
+	}
+
+	if len(skipped) > 0 {
+		fmt.Printf("\nSkipped %d requirements outside of the requested organizations, clone them by module path if needed\n", len(skipped))
+	}
+
+	fmt.Println()
+	includes := make(map[string]bool)
+	for _, record := range records {
+		if record.Result == "skipped" {
+			continue
+		}
+
+		if dir := filepath.Dir(record.Path); !includes[dir] {
+			includes[dir] = true
+			fmt.Printf("Next: `gomu list -i %s`\n", dir)
+		}
+	}
+
+	exitWithFailures(failures)
+	return
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/hatchify/simply"
+)
+
+func TestRemoteURL(context *testing.T) {
+	test := simply.Target(remoteURL("github.com/hatchify/parg/v2"), context, "Remote should be the https repository root")
+	result := test.Equals("https://github.com/hatchify/parg.git")
+	test.Validate(result)
+
+	cloneSSH = true
+	defer func() { cloneSSH = false }()
+
+	test = simply.Target(remoteURL("github.com/hatchify/parg"), context, "Remote should use ssh with -ssh")
+	result = test.Equals("git@github.com:hatchify/parg.git")
+	test.Validate(result)
+
+	test = simply.Target(orgOf("github.com/hatchify/parg/v2"), context, "Org should drop the repository")
+	result = test.Equals("github.com/hatchify")
+	test.Validate(result)
+}
+
+func TestClone_Dir(context *testing.T) {
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+
+	os.Setenv("HOME", "/home/gomu")
+
+	test := simply.Target(cloneDir("github.com/hatchify/parg/v2"), context, "Modules should be cloned into ~/go/src like upgrade, without their major version")
+	result := test.Equals(filepath.FromSlash("/home/gomu/go/src/github.com/hatchify/parg"))
+	test.Validate(result)
+}
+
+func TestClone_SkipsOtherOrganizations(context *testing.T) {
+	home, err := ioutil.TempDir("", "gomu-home")
+	if err != nil {
+		context.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	saved := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", saved)
+
+	// Both hatchify libraries exist already, so nothing is cloned
+	hatchify := filepath.Join(home, "go", "src", "github.com", "hatchify")
+	writeLibrary(context, hatchify, "parg", "module github.com/hatchify/parg\n\nrequire (\n\tgithub.com/hatchify/scribe v0.4.84\n\tgolang.org/x/mod v0.3.0\n)\n")
+	writeLibrary(context, hatchify, "scribe", "module github.com/hatchify/scribe\n\nrequire golang.org/x/mod v0.3.0\n")
+
+	records, err := ioutil.TempFile("", "gomu-clone")
+	if err != nil {
+		context.Fatal(err)
+	}
+	defer os.Remove(records.Name())
+
+	outputJSON, jsonOut = true, records
+	defer func() { outputJSON, jsonOut = false, os.Stdout }()
+
+	if err = runClone(gomu.Options{FilterDependencies: []string{"github.com/hatchify/parg"}}); err != nil {
+		context.Fatal(err)
+	}
+
+	records.Seek(0, io.SeekStart)
+	decoder := json.NewDecoder(records)
+
+	var results []string
+	for decoder.More() {
+		var record cloneRecord
+		decoder.Decode(&record)
+		results = append(results, record.Module+" "+record.Result)
+	}
+
+	test := simply.Target(results, context, "Requirements outside of hatchify should be listed once as skipped")
+	result := test.Equals([]string{
+		"github.com/hatchify/parg exists",
+		"golang.org/x/mod skipped",
+		"github.com/hatchify/scribe exists",
+	})
+	test.Validate(result)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	gomu "github.com/gomuserver/mod-utils"
 	"github.com/gomuserver/mod-utils/com"
@@ -19,15 +20,39 @@ func configureCommand() (cmd *flag.Command, err error) {
 	parg.AddAction("help", "Prints available commands and flags.\n  Use `gomu help <command> <flags>` to get more specific info.")
 	parg.AddAction("version", "Prints current version.\n  Install using `gomu upgrade` to get version support.")
 
-	parg.AddAction("list", "Prints each file in dependency chain.")
+	parg.AddAction("list", "Prints each file in dependency chain.\n  Arguments may be names, module paths, globs or /regexes/.\n  Dependency cycles are printed first, their libraries are listed in an arbitrary order.\n  Usage: `gomu list svc-*` or `gomu list /^mod-/`")
 	parg.AddAction("pull", "Updates branch for file in dependency chain.\n  Providing a -branch will checkout given branch.\n  Creates branch if provided none exists.")
+	parg.AddAction("checkout", "Switches each library to -branch if it exists locally or remotely.\n  Otherwise falls back to -default-branch (master by default).\n  Never creates branches.\n  Usage: `gomu checkout -b feature/Jira-Ticket -default develop`")
 
-	parg.AddAction("replace", "Replaces each versioned file in the dependency chain.\n  Uses the current checked out local copy.")
+	parg.AddAction("status", "Prints a row per library in the dependency chain.\n  Shows branch, local changes, ahead/behind upstream, latest tag,\n  commits since that tag and active replace directives.\n  Usage: `gomu status -i hatchify`")
+	parg.AddAction("log", "Prints commits across the dependency chain, newest first.\n  Lists commits since each library's latest tag, or since -from.\n  Filter with -author or -grep.\n  Usage: `gomu log -i hatchify -from v0.5.0 -author jane`")
+	parg.AddAction("diff", "Prints uncommitted changes across the dependency chain.\n  With -mod-only, summarizes go.mod changes by module instead.\n  Usage: `gomu diff -i hatchify -mod-only`")
+
+	parg.AddAction("graph", "Prints the dependency chain as a graph.\n  Edges show the required version and any active replace.\n  Supports -format dot (default), mermaid or json.\n  Usage: `gomu graph mod-utils -format mermaid`")
+
+	parg.AddAction("why", "Prints every path from the selected libraries to a module.\n  Includes the go.mod line and version pulling in each step.\n  Usage: `gomu why github.com/hatchify/parg` or `gomu why parg mod-utils`")
+
+	parg.AddAction("outdated", "Prints requirements older than the latest tag of their local copy.\n  Grouped by dependency and by consumer.\n  Usage: `gomu outdated -i hatchify`")
+
+	parg.AddAction("drift", "Prints modules required at different versions within the chain.\n  Shows a matrix of consumers and versions.\n  With -fix, aligns every consumer on the highest version, then syncs.\n  Usage: `gomu drift -i hatchify` or `gomu drift -fix -c -pr`")
+
+	parg.AddAction("check-cycles", "Prints any dependency cycles between libraries.\n  Shows the go.mod lines forming each cycle.\n  Exits with code 5 if a cycle exists.\n  Usage: `gomu check-cycles -i hatchify -i vroomy`")
+
+	parg.AddAction("stash", "Stashes uncommitted work in every library under one gomu stash entry.\n  Names the entry with -message, and stamps it with the current time.\n  Usage: `gomu stash -i hatchify -m before-sync`")
+	parg.AddAction("unstash", "Restores the newest gomu stash entry in every library that has it.\n  Restores the newest entry named -message if provided.\n  Reports conflicts per library, keeping their stash entry.\n  Usage: `gomu unstash -i hatchify -m before-sync`")
+	parg.AddAction("snapshot", "Prints a manifest of the module path, remote, branch and commit of each library.\n  Writes json, or yaml with -format yaml.\n  Usage: `gomu snapshot -i hatchify -format yaml > release.yml`")
+	parg.AddAction("restore", "Checks every library of a manifest out to its recorded commit.\n  Clones missing libraries, and refuses to touch uncommitted changes.\n  Additional arguments limit which libraries are restored.\n  Usage: `gomu restore release.yml` or `gomu restore release.yml parg scribe`")
+	parg.AddAction("clone", "Clones module paths, or the libraries of a manifest, into ~/go/src, like upgrade.\n  Then clones missing dependencies from the same organizations, found in their go.mod files.\n  Requirements from other organizations are listed as skipped.\n  Limit how many hops are followed with -depth, and clone over ssh with -ssh.\n  Usage: `gomu clone github.com/hatchify/vroomy` or `gomu clone release.yml`")
+	parg.AddAction("exec", "Runs a command in each library of the dependency chain.\n  Runs in dependency order, concurrently per level with -parallel.\n  Prints a pass/fail table once complete.\n  Usage: `gomu exec -i hatchify -- go vet ./...` or `gomu exec -- \"make lint | tee lint.log\"`")
+	parg.AddAction("replace", "Replaces each versioned file in the dependency chain.\n  Uses the current checked out local copy.\n  Use work instead to leave go.mod untouched.")
+	parg.AddAction("work", "Writes a go.work file using the local copy of each library in the dependency chain.\n  Overrides versions like replace, without editing any go.mod.\n  Written to the working directory, or to -source (a directory or file).\n  Never replaces an existing go.work without -force.\n  Warns about other modules beneath it, which go commands can no longer build.\n  Usage: `gomu work -i hatchify -i vroomy` or `gomu work -source ~/dev`")
 	parg.AddAction("reset", "Reverts go.mod and go.sum back to last committed version.\n  Usage: `gomu reset mod-common parg`")
 	parg.AddAction("test", "Runs `go test` on each library in the dependency chain.\n  Prints names of failing libraries.\n  Usage: `gomu test mod-common`")
 
 	parg.AddAction("sync", "Updates modfiles.\n  Conditionally performs extra tasks depending on flags.\n  Usage: `gomu <flags> sync mod-common parg simply <flags>`")
 
+	parg.AddAction("prune-branches", "Deletes branches merged into -default-branch, or whose upstream is gone.\n  Lists stale branches and asks for confirmation first.\n  Gone branches holding commits on no remote are kept unless merged into the current branch.\n  Includes merged remote branches with -remotes.\n  Usage: `gomu prune-branches -i hatchify -remotes`")
+
 	parg.AddAction("workflow", "Adds a github workflow to a repo.\n  Requires -source <template path>.\n  Usage: `gomu workflow mod-utils -c -b new-workflow -source workflows/templates/autotag.yml`")
 	//parg.AddAction("secret", "Adds a secret to a repo's github actions.\n  Requires -source <file containing secret>.\n  Usage: `gomu secret mod-utils -source ~/.ssh/server_key.crt`")
 
@@ -40,23 +65,117 @@ func configureCommand() (cmd *flag.Command, err error) {
 		Type:        flag.STRINGS,
 		Help:        "Will aggregate files in 1 or more directories.\n  Usage: `gomu list -i hatchify -i vroomy`",
 	})
+	parg.AddGlobalFlag(flag.Flag{ // Fallback branch for checkout
+		Name:        "-default-branch",
+		Identifiers: []string{"-default", "-default-branch"},
+		Help:        "Will set the branch checkout falls back to, and prune-branches compares against.\n  Defaults to master.\n  Usage: `gomu checkout -b feature/Jira-Ticket -default develop`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Include remote branches when pruning
+		Name:        "-remotes",
+		Identifiers: []string{"-remotes"},
+		Type:        flag.BOOL,
+		Help:        "Will also delete merged branches from the remote in prune-branches.\n  Usage: `gomu prune-branches -remotes`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Clone over ssh
+		Name:        "-ssh",
+		Identifiers: []string{"-ssh"},
+		Type:        flag.BOOL,
+		Help:        "Will clone over ssh instead of https.\n  Usage: `gomu clone -ssh github.com/hatchify/vroomy`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Libraries or directories to skip
+		Name:        "-exclude",
+		Identifiers: []string{"-x", "-exclude"},
+		Type:        flag.STRINGS,
+		Help:        "Will drop matching libraries or directories from the chain.\n  Accepts names, module paths, globs or /regexes/.\n  Patterns in a .gomuignore file within an included directory also apply.\n  Excluded libraries are never processed, even as dependencies of selected ones.\n  mod-utils runs over a directory linking only the selected libraries.\n  replace runs in gomu instead, so replacements point at the working copies rather than those links.\n  Usage: `gomu sync -i hatchify -x archive-* -x /-experimental$/`",
+	})
 	parg.AddGlobalFlag(flag.Flag{ // Branch to checkout/create
 		Name:        "-branch",
 		Identifiers: []string{"-b", "-branch"},
 		Help:        "Will checkout or create said branch.\n  Updating or creating a pull request.\n  Depending on command and other flags.\n  Usage: `gomu pull -b feature/Jira-Ticket`",
 	})
+	parg.AddGlobalFlag(flag.Flag{ // Walk up the chain instead of down
+		Name:        "-dependents",
+		Identifiers: []string{"-dependents"},
+		Type:        flag.BOOL,
+		Help:        "Will select every library depending on the arguments instead.\n  Libraries are still processed in dependency order.\n  Other dependencies of the dependents are left untouched.\n  Usage: `gomu sync -dependents mod-utils -c -pr`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Incremental runs
+		Name:        "-since",
+		Identifiers: []string{"-since"},
+		Help:        "Will limit the chain to libraries changed since a ref, tag, date or duration.\n  Uncommitted changes count, and dependents of changed libraries are included.\n  Unchanged dependencies are left untouched.\n  Usage: `gomu test -since v0.5.0` or `gomu test -since 8h`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Bounded traversal
+		Name:        "-depth",
+		Identifiers: []string{"-depth"},
+		Help:        "Will only traverse N levels of dependencies from the selected libraries.\n  0 selects the arguments alone, 1 adds their direct dependencies.\n  list prints the depth of each library, and clone follows at most N hops.\n  Usage: `gomu list mod-utils -depth 2`",
+	})
 	parg.AddGlobalFlag(flag.Flag{ // Minimal output for | chains
 		Name:        "-direct-import",
 		Identifiers: []string{"-direct", "-direct-import"},
 		Type:        flag.BOOL,
 		Help:        "Will avoid recursion in dependency sorting.\n  Only includes deps in go.mod (not go.sum).\n  Usage: `gomu list mod-utils -direct`",
 	})
+	parg.AddGlobalFlag(flag.Flag{ // Concurrent libraries per level
+		Name:        "-parallel",
+		Identifiers: []string{"-p", "-parallel"},
+		Help:        "Will process up to N libraries at the same depth concurrently.\n  Applies to list, pull, test, replace, reset, exec and checkout.\n  sync switches branches, requires the latest tags and tidies concurrently,\n  then commits, tags and pushes serially through mod-utils.\n  workflow always runs serially.\n  Output is printed per library once its level completes.\n  Usage: `gomu test -i hatchify -parallel 8`",
+	})
 	parg.AddGlobalFlag(flag.Flag{ // Minimal output for | chains
 		Name:        "-name-only",
 		Identifiers: []string{"-name", "-name-only"},
 		Type:        flag.BOOL,
 		Help:        "Will reduce output to just the filenames changed.\n  (ls-styled output for | chaining)\n  Usage: `gomu list -name`",
 	})
+	parg.AddGlobalFlag(flag.Flag{ // Structured output for tooling
+		Name:        "-json",
+		Identifiers: []string{"-json"},
+		Type:        flag.BOOL,
+		Help:        "Will print one json record per library, then a summary record.\n  Human readable output is moved to stderr.\n  Usage: `gomu sync -json`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Prints planned changes without making them
+		Name:        "-dry-run",
+		Identifiers: []string{"-dry", "-dry-run"},
+		Type:        flag.BOOL,
+		Help:        "Will print each change sync, workflow or pull would make.\n  Includes go.mod edits, commits, branches, pushes, tags and pull requests.\n  No working copy is modified.\n  The plan re-implements the decisions of mod-utils, so a real run may differ.\n  Exec, checkout, stash, unstash, restore, clone, work and prune-branches report what they would do instead.\n  Usage: `gomu sync -c -pr -t -dry-run`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Output format for report commands
+		Name:        "-format",
+		Identifiers: []string{"-f", "-format"},
+		Help:        "Will set the output format of report commands.\n  Accepts dot, mermaid or json for graph, and json or yaml for snapshot.\n  Usage: `gomu graph -format mermaid`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Aligns drifting versions
+		Name:        "-fix",
+		Identifiers: []string{"-fix"},
+		Type:        flag.BOOL,
+		Help:        "Will align drifting modules on their highest version.\n  Runs sync afterwards, so sync flags apply.\n  Usage: `gomu drift -fix -c`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Semantic go.mod diffs
+		Name:        "-mod-only",
+		Identifiers: []string{"-mod", "-mod-only"},
+		Type:        flag.BOOL,
+		Help:        "Will show go.mod changes as version and replace changes in diff.\n  Usage: `gomu diff -mod-only`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Overwrite an existing go.work
+		Name:        "-force",
+		Identifiers: []string{"-force"},
+		Type:        flag.BOOL,
+		Help:        "Will replace an existing go.work file in work.\n  Usage: `gomu work -i hatchify -force`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Starting point of log
+		Name:        "-from",
+		Identifiers: []string{"-from"},
+		Help:        "Will list commits since a ref, tag, date or duration in log, instead of the latest tag.\n  Libraries where the ref does not exist are skipped.\n  Usage: `gomu log -from v0.5.0` or `gomu log -from 2w`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Filters log by author
+		Name:        "-author",
+		Identifiers: []string{"-author"},
+		Help:        "Will only include commits by a matching author in log.\n  Usage: `gomu log -author jane`",
+	})
+	parg.AddGlobalFlag(flag.Flag{ // Filters log by message
+		Name:        "-grep",
+		Identifiers: []string{"-grep"},
+		Help:        "Will only include commits with a matching message in log.\n  Accepts a regular expression, matched case insensitively.\n  Usage: `gomu log -grep \"^fix\"`",
+	})
 	parg.AddGlobalFlag(flag.Flag{ // Commits local changes
 		Name:        "-commit",
 		Identifiers: []string{"-c", "-commit"},
@@ -72,7 +191,7 @@ func configureCommand() (cmd *flag.Command, err error) {
 	parg.AddGlobalFlag(flag.Flag{ // Branch to checkout/create
 		Name:        "-message",
 		Identifiers: []string{"-m", "-msg", "-message"},
-		Help:        "Will set a custom commit message.\n  Applies to -c and -pr flags.\n  Usage: `gomu sync -c -m \"Update all the things!\"`",
+		Help:        "Will set a custom commit message.\n  Applies to -c and -pr flags, and names stash entries.\n  {action}, {branch} and {date} are replaced by the command, -branch and today's date.\n  Usage: `gomu sync -c -m \"Update all the things!\"`",
 	})
 	parg.AddGlobalFlag(flag.Flag{ // Update tag/version for changed libs or subdeps
 		Name:        "-tag",
@@ -88,13 +207,16 @@ func configureCommand() (cmd *flag.Command, err error) {
 	parg.AddGlobalFlag(flag.Flag{ // Update tag/version for changed libs or subdeps
 		Name:        "-source-path",
 		Identifiers: []string{"-s", "-source", "-source-path"},
-		Help:        "Required for workflow and secret commands.\n  Will provide a source template or secret file.\n  Usage: `gomu workflow mod-utils -source path/to/template.yml`",
+		Help:        "Required for workflow and secret commands.\n  Will provide a source template or secret file.\n  Sets where work writes the go.work file.\n  Usage: `gomu workflow mod-utils -source path/to/template.yml`",
 	})
 
 	return flag.Validate()
 }
 
 func gomuOptions() (options gomu.Options) {
+	// Keep the exec command away from the flag parser
+	os.Args, execArgs = splitExecArgs(os.Args)
+
 	// Get command from args
 	cmd, err := configureCommand()
 
@@ -102,12 +224,12 @@ func gomuOptions() (options gomu.Options) {
 		// Show usage and exit with error
 		showHelp(nil)
 		com.Errorln("Error parsing arguments: ", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 	if cmd == nil {
 		showHelp(cmd)
 		com.Errorln("Error parsing command: ", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	switch cmd.Action {
@@ -127,27 +249,82 @@ func gomuOptions() (options gomu.Options) {
 	// Parse options from cmd
 	options.Action = cmd.Action
 
+	// Defaults from .gomu.yml
+	if workspace, err = loadConfig(); err != nil {
+		com.Errorln("Error reading config: ", err)
+		os.Exit(exitUsage)
+	}
+
+	if remote := workspace.String("remote"); len(remote) > 0 {
+		remoteName = remote
+	}
+
 	// Args
 	options.FilterDependencies = make([]string, len(cmd.Arguments))
 	for i, argument := range cmd.Arguments {
 		options.FilterDependencies[i] = argument.Name
 	}
 
+	if options.FilterDependencies, err = filtersFromInput(options.FilterDependencies, os.Stdin); err != nil {
+		com.Errorln("Error parsing arguments: ", err)
+		os.Exit(exitUsage)
+	}
+
+	if options.FilterDependencies, err = expandGroups(options.FilterDependencies); err != nil {
+		com.Errorln("Error parsing arguments: ", err)
+		os.Exit(exitUsage)
+	}
+
 	// Flags
-	options.TargetDirectories = cmd.StringsFrom("-include")
+	options.TargetDirectories = stringsOption(cmd, "-include")
+	excludePatterns = stringsOption(cmd, "-exclude")
+	if err = validatePatterns(append(excludePatterns, options.FilterDependencies...)); err != nil {
+		com.Errorln("Error parsing arguments: ", err)
+		os.Exit(exitUsage)
+	}
+
+	options.Branch = stringOption(cmd, "-branch")
+	options.CommitMessage = expandMessage(stringOption(cmd, "-message"), options, time.Now())
+
+	options.Commit = boolOption(cmd, "-commit")
+	options.PullRequest = boolOption(cmd, "-pull-request")
+	options.Tag = boolOption(cmd, "-tag")
+	options.SetVersion = stringOption(cmd, "-set-version")
+
+	options.SourcePath = stringOption(cmd, "-source-path")
+
+	options.DirectImport = boolOption(cmd, "-direct-import")
+	if branch := stringOption(cmd, "-default-branch"); len(branch) > 0 {
+		defaultBranch = branch
+	}
 
-	options.Branch = cmd.StringFrom("-branch")
-	options.CommitMessage = cmd.StringFrom("-message")
+	pruneRemotes = boolOption(cmd, "-remotes")
+	cloneSSH = boolOption(cmd, "-ssh")
 
-	options.Commit = cmd.BoolFrom("-commit")
-	options.PullRequest = cmd.BoolFrom("-pull-request")
-	options.Tag = cmd.BoolFrom("-tag")
-	options.SetVersion = cmd.StringFrom("-set-version")
+	selectDependents = boolOption(cmd, "-dependents")
+	sinceRef = stringOption(cmd, "-since")
+	if maxDepth, err = parseDepth(stringOption(cmd, "-depth")); err != nil {
+		showHelp(cmd)
+		com.Errorln("Error parsing arguments: ", err)
+		os.Exit(exitUsage)
+	}
 
-	options.SourcePath = cmd.StringFrom("-source-path")
+	if parallel, err = parseParallel(stringOption(cmd, "-parallel")); err != nil {
+		showHelp(cmd)
+		com.Errorln("Error parsing arguments: ", err)
+		os.Exit(exitUsage)
+	}
 
-	options.DirectImport = cmd.BoolFrom("-direct-import")
-	nameOnly := cmd.BoolFrom("-name-only")
+	outputJSON = boolOption(cmd, "-json")
+	dryRun = boolOption(cmd, "-dry-run")
+	outputFormat = stringOption(cmd, "-format")
+	fixDrift = boolOption(cmd, "-fix")
+	forceWork = boolOption(cmd, "-force")
+	modOnly = boolOption(cmd, "-mod-only")
+	logFrom = stringOption(cmd, "-from")
+	logAuthor = stringOption(cmd, "-author")
+	logGrep = stringOption(cmd, "-grep")
+	nameOnly := boolOption(cmd, "-name-only")
 	if nameOnly {
 		options.LogLevel = com.NAMEONLY
 	} else {
@@ -165,5 +342,30 @@ func fromArgs() *gomu.MU {
 		options.TargetDirectories = []string{"."}
 	}
 
-	return gomu.New(options)
+	if outputJSON {
+		// Keep stdout clean for json records
+		os.Stdout = os.Stderr
+	}
+
+	runLocalAction(options)
+
+	if err := checkRemote(options); err != nil {
+		com.Errorln(err)
+		os.Exit(exitUsage)
+	}
+
+	if dryRun {
+		if err := printPlan(options); err != nil {
+			com.Errorln(err)
+			os.Exit(exitUsage)
+		}
+
+		os.Exit(0)
+	}
+
+	if runsLocally(options) {
+		runParallel(options)
+	}
+
+	return newMU(options)
 }
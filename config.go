@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	gomu "github.com/gomuserver/mod-utils"
+	flag "github.com/hatchify/parg"
+)
+
+// configNames are the workspace config files gomu looks for, in order of preference
+var configNames = []string{".gomu.yml", ".gomu.yaml"}
+
+// unsafeConfigKeys publish, tag, delete, overwrite or change what is selected and printed, so they must be passed on the command line
+// A bool flag can only be turned on, so a default of true could never be overridden
+var unsafeConfigKeys = []string{"commit", "pull-request", "tag", "set-version", "fix", "remotes", "force", "name-only", "dependents", "json", "dry-run"}
+
+// workspace holds defaults loaded from the nearest .gomu.yml
+var workspace = newConfig()
+
+// config holds flag defaults and library groups read from a .gomu.yml file
+//
+//	include:
+//	  - hatchify
+//	  - vroomy
+//	branch: develop
+//	direct-import: true
+//	remote: upstream
+//	groups:
+//	  core: [parg, scribe, simply]
+type config struct {
+	// Path is the config file, empty if none was found
+	Path string
+
+	values map[string]string
+	lists  map[string][]string
+	groups map[string][]string
+}
+
+func newConfig() *config {
+	return &config{
+		values: make(map[string]string),
+		lists:  make(map[string][]string),
+		groups: make(map[string][]string),
+	}
+}
+
+// String returns the value set for key
+func (cfg *config) String(key string) string {
+	return cfg.values[key]
+}
+
+// Bool returns true if key is set to true, yes, on or 1
+func (cfg *config) Bool(key string) bool {
+	switch strings.ToLower(cfg.values[key]) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+
+	return false
+}
+
+// Strings returns the list set for key
+// A single value is treated as a list of one
+func (cfg *config) Strings(key string) []string {
+	if list, ok := cfg.lists[key]; ok {
+		return list
+	}
+
+	if value, ok := cfg.values[key]; ok {
+		return []string{value}
+	}
+
+	return nil
+}
+
+// Group returns the libraries of the named group
+func (cfg *config) Group(name string) (libs []string, ok bool) {
+	libs, ok = cfg.groups[name]
+	return
+}
+
+// findConfig looks for a config file in dir, then each of its parents
+func findConfig(dir string) (filename string, ok bool) {
+	for {
+		for _, name := range configNames {
+			filename = filepath.Join(dir, name)
+			if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+				return filename, true
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+
+		dir = parent
+	}
+}
+
+// loadConfig reads the nearest config file from the working directory upward
+// Relative include directories are resolved against the config file
+func loadConfig() (cfg *config, err error) {
+	var dir string
+	if dir, err = os.Getwd(); err != nil {
+		return
+	}
+
+	filename, ok := findConfig(dir)
+	if !ok {
+		return newConfig(), nil
+	}
+
+	var f *os.File
+	if f, err = os.Open(filename); err != nil {
+		return
+	}
+	defer f.Close()
+
+	if cfg, err = parseConfig(f); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	cfg.Path = filename
+	if include := cfg.Strings("include"); len(include) > 0 {
+		resolved := make([]string, len(include))
+		for i, dir := range include {
+			if filepath.IsAbs(dir) {
+				resolved[i] = dir
+			} else {
+				resolved[i] = filepath.Join(filepath.Dir(filename), dir)
+			}
+		}
+
+		cfg.lists["include"] = resolved
+	}
+
+	return
+}
+
+// parseConfig reads the subset of yaml gomu supports:
+// top level scalars and lists, and a groups map of lists
+func parseConfig(r io.Reader) (cfg *config, err error) {
+	cfg = newConfig()
+
+	var (
+		key    string
+		group  string
+		lineNo int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		line := stripConfigComment(scanner.Text())
+		text := strings.TrimSpace(line)
+		if len(text) == 0 || text == "---" {
+			continue
+		}
+
+		indented := len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+
+		switch {
+		case !indented && !strings.HasPrefix(text, "-"):
+			k, value, ok := splitConfigKey(text)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected `key: value`", lineNo)
+			}
+
+			key, group = k, ""
+			if len(value) > 0 {
+				cfg.setValue(key, value)
+			}
+
+		case strings.HasPrefix(text, "-"):
+			item := unquoteConfig(strings.TrimSpace(strings.TrimPrefix(text, "-")))
+			switch {
+			case len(group) > 0:
+				cfg.groups[group] = append(cfg.groups[group], item)
+			case len(key) > 0:
+				cfg.lists[key] = append(cfg.lists[key], item)
+			default:
+				return nil, fmt.Errorf("line %d: list item without a key", lineNo)
+			}
+
+		case key == "groups":
+			name, value, ok := splitConfigKey(text)
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected `group: [libraries]`", lineNo)
+			}
+
+			group = name
+			cfg.groups[group] = parseConfigList(value)
+
+		default:
+			return nil, fmt.Errorf("line %d: unexpected indentation under %s", lineNo, key)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	for _, key := range unsafeConfigKeys {
+		if _, ok := cfg.values[key]; ok {
+			return nil, fmt.Errorf("%s cannot be set in the config, pass -%s on the command line instead", key, key)
+		}
+	}
+
+	return
+}
+
+func (cfg *config) setValue(key, value string) {
+	if strings.HasPrefix(value, "[") {
+		cfg.lists[key] = parseConfigList(value)
+		return
+	}
+
+	cfg.values[key] = unquoteConfig(value)
+}
+
+// splitConfigKey splits `key: value`
+func splitConfigKey(text string) (key, value string, ok bool) {
+	i := strings.Index(text, ":")
+	if i < 1 {
+		return
+	}
+
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+}
+
+// parseConfigList parses an inline `[a, b]` list
+func parseConfigList(value string) (list []string) {
+	list = []string{}
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]")
+	for _, item := range strings.Split(value, ",") {
+		if item = unquoteConfig(strings.TrimSpace(item)); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+
+	return
+}
+
+// unquoteConfig removes surrounding quotes, unescaping double quoted values written with %q
+func unquoteConfig(value string) string {
+	if len(value) < 2 || (value[0] != '"' && value[0] != '\'') || value[len(value)-1] != value[0] {
+		return value
+	}
+
+	if value[0] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+
+	return value[1 : len(value)-1]
+}
+
+// stripConfigComment removes a trailing # comment outside of quotes
+func stripConfigComment(line string) string {
+	var (
+		quote   rune
+		escaped bool
+	)
+
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+
+	return strings.TrimRight(line, " \t")
+}
+
+// configKey returns the config key for a flag name
+func configKey(name string) string {
+	return strings.TrimPrefix(name, "-")
+}
+
+// stringOption returns the flag value, falling back to the workspace config
+func stringOption(cmd *flag.Command, name string) string {
+	if value := cmd.StringFrom(name); len(value) > 0 {
+		return value
+	}
+
+	return workspace.String(configKey(name))
+}
+
+// stringsOption returns the flag values, falling back to the workspace config
+func stringsOption(cmd *flag.Command, name string) []string {
+	if values := cmd.StringsFrom(name); len(values) > 0 {
+		return values
+	}
+
+	return workspace.Strings(configKey(name))
+}
+
+// boolOption returns true if the flag or the workspace config is set
+func boolOption(cmd *flag.Command, name string) bool {
+	return cmd.BoolFrom(name) || workspace.Bool(configKey(name))
+}
+
+// expandMessage fills the placeholders of a commit message template
+// {action} is the command, {branch} is -branch and {date} is today as YYYY-MM-DD
+func expandMessage(template string, options gomu.Options, now time.Time) string {
+	return strings.NewReplacer(
+		"{action}", options.Action,
+		"{branch}", options.Branch,
+		"{date}", now.Format("2006-01-02"),
+	).Replace(template)
+}
+
+// expandGroups replaces each @group argument with the libraries of that group
+func expandGroups(args []string) (expanded []string, err error) {
+	expanded = make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		libs, ok := workspace.Group(strings.TrimPrefix(arg, "@"))
+		if !ok {
+			return nil, fmt.Errorf("unknown library group %s", arg)
+		}
+
+		expanded = append(expanded, libs...)
+	}
+
+	return
+}
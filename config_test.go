@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/hatchify/simply"
+)
+
+func TestWorkspaceConfig_Parse(context *testing.T) {
+	input := `# gomu workspace
+include:
+  - hatchify
+  - "vroomy" # quoted
+branch: develop
+message: "Update #deps"
+direct-import: yes
+groups:
+  core: [parg, scribe]
+  services:
+    - svc-a
+    - svc-b
+`
+
+	cfg, err := parseConfig(strings.NewReader(input))
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(cfg.Strings("include"), context, "Include should have 2 directories")
+	result = test.Equals([]string{"hatchify", "vroomy"})
+	test.Validate(result)
+
+	test = simply.Target(cfg.String("branch"), context, "Branch should be develop")
+	result = test.Equals("develop")
+	test.Validate(result)
+
+	test = simply.Target(cfg.String("message"), context, "Quoted # should not start a comment")
+	result = test.Equals("Update #deps")
+	test.Validate(result)
+
+	test = simply.Target(cfg.Bool("direct-import"), context, "Direct import should be true")
+	result = test.Equals(true)
+	test.Validate(result)
+
+	core, _ := cfg.Group("core")
+	test = simply.Target(core, context, "Core group should be inline list")
+	result = test.Equals([]string{"parg", "scribe"})
+	test.Validate(result)
+
+	services, _ := cfg.Group("services")
+	test = simply.Target(services, context, "Services group should be block list")
+	result = test.Equals([]string{"svc-a", "svc-b"})
+	test.Validate(result)
+}
+
+func TestWorkspaceConfig_UnsafeKeys(context *testing.T) {
+	// Bool flags changing the selection or output could not be turned off either
+	for _, key := range []string{"name-only", "dependents", "json", "dry-run"} {
+		_, err := parseConfig(strings.NewReader(key + ": true\n"))
+
+		test := simply.Target(err != nil, context, key+" should be refused in the config")
+		result := test.Equals(true)
+		test.Validate(result)
+	}
+
+	for _, key := range unsafeConfigKeys {
+		_, err := parseConfig(strings.NewReader("branch: develop\n" + key + ": true\n"))
+
+		test := simply.Target(err != nil, context, key+" should be refused in the config")
+		result := test.Equals(true)
+		test.Validate(result)
+	}
+}
+
+func TestWorkspaceConfig_ExpandMessage(context *testing.T) {
+	options := gomu.Options{Action: "sync", Branch: "feature/deps"}
+	now := time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	test := simply.Target(expandMessage("{action}: update {branch} on {date}", options, now), context, "Message placeholders should be replaced")
+	result := test.Equals("sync: update feature/deps on 2020-05-01")
+	test.Validate(result)
+
+	test = simply.Target(expandMessage("Update all the things!", options, now), context, "Plain messages should be unchanged")
+	result = test.Equals("Update all the things!")
+	test.Validate(result)
+}
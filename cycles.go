@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// destructiveActions commit and push changes, so they refuse to run on a cyclic chain
+var destructiveActions = map[string]bool{
+	"sync":     true,
+	"workflow": true,
+}
+
+// cycle is a set of libraries which all (transitively) require each other
+type cycle struct {
+	Type      string   `json:"type"`
+	Libraries []string `json:"libraries"`
+	// Edges are the go.mod (or go.sum) lines linking the libraries together
+	Edges []whyHop `json:"edges"`
+}
+
+// findCycles returns each cycle within chain, using Tarjan's strongly connected components
+func findCycles(chain []*library, direct bool) (cycles []cycle) {
+	inChain := make(map[*library]bool, len(chain))
+	for _, lib := range chain {
+		inChain[lib] = true
+	}
+
+	var (
+		index   int
+		stack   []*library
+		indexes = make(map[*library]int)
+		lowest  = make(map[*library]int)
+		onStack = make(map[*library]bool)
+	)
+
+	var connect func(lib *library)
+	connect = func(lib *library) {
+		indexes[lib] = index
+		lowest[lib] = index
+		index++
+
+		stack = append(stack, lib)
+		onStack[lib] = true
+
+		for _, dep := range lib.Deps {
+			if !inChain[dep] {
+				continue
+			}
+
+			if _, visited := indexes[dep]; !visited {
+				connect(dep)
+				if lowest[dep] < lowest[lib] {
+					lowest[lib] = lowest[dep]
+				}
+			} else if onStack[dep] && indexes[dep] < lowest[lib] {
+				lowest[lib] = indexes[dep]
+			}
+		}
+
+		if lowest[lib] != indexes[lib] {
+			return
+		}
+
+		var component []*library
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == lib {
+				break
+			}
+		}
+
+		if len(component) > 1 {
+			cycles = append(cycles, newCycle(component, direct))
+		}
+	}
+
+	for _, lib := range chain {
+		if _, visited := indexes[lib]; !visited {
+			connect(lib)
+		}
+	}
+
+	return
+}
+
+func newCycle(component []*library, direct bool) (c cycle) {
+	c.Type = "cycle"
+	sort.Slice(component, func(i, j int) bool {
+		return component[i].Name < component[j].Name
+	})
+
+	inCycle := make(map[*library]bool, len(component))
+	for _, lib := range component {
+		inCycle[lib] = true
+		c.Libraries = append(c.Libraries, lib.Name)
+	}
+
+	for _, lib := range component {
+		for _, dep := range lib.Deps {
+			if !inCycle[dep] {
+				continue
+			}
+
+			if hop, ok := hopTo(lib, dep.Module, direct); ok {
+				c.Edges = append(c.Edges, hop)
+			}
+		}
+	}
+
+	return
+}
+
+// printCycles prints the libraries and edges forming each cycle
+func printCycles(cycles []cycle) {
+	for _, c := range cycles {
+		com.Errorln(fmt.Sprintf("Dependency cycle between %d libraries:", len(c.Libraries)))
+		for _, edge := range c.Edges {
+			com.Errorln(fmt.Sprintf("  %s: %s", edge.Library, edge.String()))
+		}
+	}
+}
+
+// checkCycles warns about cycles within the chain selected by options
+// Destructive actions exit instead of running in an arbitrary order
+func checkCycles(options gomu.Options) {
+	chain, err := loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport)
+	if err != nil {
+		return
+	}
+
+	cycles := findCycles(chain, options.DirectImport)
+	if len(cycles) == 0 {
+		return
+	}
+
+	printCycles(cycles)
+	if destructiveActions[options.Action] {
+		com.Errorln("Refusing to " + options.Action + " a cyclic dependency chain. Run `gomu check-cycles` for details.")
+		os.Exit(exitCycle)
+	}
+}
+
+// printCheckCycles reports every cycle in the chain, exiting with exitCycle if any exist
+func printCheckCycles(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	cycles := findCycles(chain, options.DirectImport)
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, c := range cycles {
+			encoder.Encode(c)
+		}
+	} else if len(cycles) > 0 {
+		printCycles(cycles)
+	} else {
+		fmt.Printf("No dependency cycles between %d libraries!\n", len(chain))
+	}
+
+	if len(cycles) > 0 {
+		os.Exit(exitCycle)
+	}
+
+	return
+}
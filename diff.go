@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// modOnly is set by the -mod-only flag
+var modOnly bool
+
+// modChange is a single semantic change to a go.mod file
+type modChange struct {
+	// Kind is require or replace
+	Kind   string `json:"kind"`
+	Module string `json:"module"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// String formats the change as `module old → new`, or with +/- when added or removed
+func (change modChange) String() string {
+	switch {
+	case len(change.Old) == 0:
+		return fmt.Sprintf("+ %s %s %s", change.Kind, change.Module, change.New)
+	case len(change.New) == 0:
+		return fmt.Sprintf("- %s %s %s", change.Kind, change.Module, change.Old)
+	default:
+		return fmt.Sprintf("  %s %s %s → %s", change.Kind, change.Module, change.Old, change.New)
+	}
+}
+
+// libraryDiff holds the pending changes of a single library
+type libraryDiff struct {
+	Type    string      `json:"type"`
+	Library string      `json:"library"`
+	Path    string      `json:"path"`
+	Diff    string      `json:"diff,omitempty"`
+	Changes []modChange `json:"changes,omitempty"`
+}
+
+// diffMod compares two parsed go.mod files
+func diffMod(before, after *library) (changes []modChange) {
+	oldRequires := make(map[string]string)
+	newRequires := make(map[string]string)
+	for _, req := range before.Requires {
+		oldRequires[req.Module] = req.Version
+	}
+
+	for _, req := range after.Requires {
+		newRequires[req.Module] = req.Version
+	}
+
+	oldReplaces := make(map[string]string)
+	newReplaces := make(map[string]string)
+	for _, rep := range before.Replaces {
+		oldReplaces[rep.Module] = replaceTarget(rep)
+	}
+
+	for _, rep := range after.Replaces {
+		newReplaces[rep.Module] = replaceTarget(rep)
+	}
+
+	changes = append(changes, diffDirectives("require", oldRequires, newRequires)...)
+	changes = append(changes, diffDirectives("replace", oldReplaces, newReplaces)...)
+	return
+}
+
+func replaceTarget(rep replacement) (target string) {
+	target = "=> " + rep.Target
+	if len(rep.TargetVersion) > 0 {
+		target += " " + rep.TargetVersion
+	}
+
+	return
+}
+
+func diffDirectives(kind string, before, after map[string]string) (changes []modChange) {
+	modules := make([]string, 0, len(before)+len(after))
+	for module := range before {
+		modules = append(modules, module)
+	}
+
+	for module := range after {
+		if _, ok := before[module]; !ok {
+			modules = append(modules, module)
+		}
+	}
+
+	sort.Strings(modules)
+	for _, module := range modules {
+		if before[module] != after[module] {
+			changes = append(changes, modChange{Kind: kind, Module: module, Old: before[module], New: after[module]})
+		}
+	}
+
+	return
+}
+
+// pendingDiff returns the uncommitted changes of lib
+// With -mod-only, go.mod changes are compared semantically instead
+func pendingDiff(lib *library) (diff libraryDiff, err error) {
+	diff = libraryDiff{Type: "diff", Library: lib.Name, Path: lib.Dir}
+	if !modOnly {
+		diff.Diff, err = gitOutput(lib.Dir, "diff", "HEAD")
+		return
+	}
+
+	var committed string
+	if committed, err = gitOutput(lib.Dir, "show", "HEAD:go.mod"); err != nil {
+		return
+	}
+
+	before := &library{}
+	if err = before.readMod(strings.NewReader(committed)); err != nil {
+		return
+	}
+
+	diff.Changes = diffMod(before, lib)
+	return
+}
+
+// printDiff prints pending changes across the chain
+func printDiff(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	var encoder *json.Encoder
+	if outputJSON {
+		encoder = json.NewEncoder(jsonOut)
+	}
+
+	changed := 0
+	for _, lib := range chain {
+		diff, err := pendingDiff(lib)
+		if err != nil {
+			warn(lib.Name + ": " + err.Error())
+			continue
+		}
+
+		if len(diff.Diff) == 0 && len(diff.Changes) == 0 {
+			continue
+		}
+
+		changed++
+		if encoder != nil {
+			encoder.Encode(diff)
+			continue
+		}
+
+		fmt.Printf("\n== %s ==\n", lib.Name)
+		if !modOnly {
+			fmt.Println(diff.Diff)
+			continue
+		}
+
+		for _, change := range diff.Changes {
+			fmt.Println(change.String())
+		}
+	}
+
+	if encoder == nil {
+		fmt.Printf("\n%d of %d libraries have pending changes\n", changed, len(chain))
+	}
+
+	return
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestDiff_Mod(context *testing.T) {
+	before := &library{}
+	before.readMod(strings.NewReader("module a\n\nrequire (\n\tb v0.1.0\n\tc v0.2.0\n)\n\nreplace b => ../b\n"))
+
+	after := &library{}
+	after.readMod(strings.NewReader("module a\n\nrequire (\n\tb v0.1.1\n\td v1.0.0\n)\n"))
+
+	test := simply.Target(diffMod(before, after), context, "Diff should include version, added, removed and replace changes")
+	result := test.Equals([]modChange{
+		{Kind: "require", Module: "b", Old: "v0.1.0", New: "v0.1.1"},
+		{Kind: "require", Module: "c", Old: "v0.2.0"},
+		{Kind: "require", Module: "d", New: "v1.0.0"},
+		{Kind: "replace", Module: "b", Old: "=> ../b"},
+	})
+	test.Validate(result)
+}
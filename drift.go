@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// fixDrift is set by the -fix flag
+var fixDrift bool
+
+// drift is a module required at more than one version within the chain
+type drift struct {
+	Type    string `json:"type"`
+	Module  string `json:"module"`
+	Highest string `json:"highest"`
+	// Versions maps each required version to the names of its consumers
+	Versions map[string][]string `json:"versions"`
+
+	// consumers is keyed by module, as forks of a library share its name
+	consumers map[string]*library
+}
+
+// SortedVersions returns the required versions, lowest first
+func (d *drift) SortedVersions() (versions []string) {
+	for version := range d.Versions {
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+
+	return
+}
+
+// Consumers returns each library requiring the module, sorted by name then module
+func (d *drift) Consumers() (libs []*library) {
+	for _, lib := range d.consumers {
+		libs = append(libs, lib)
+	}
+
+	sort.Slice(libs, func(i, j int) bool {
+		if libs[i].Name != libs[j].Name {
+			return libs[i].Name < libs[j].Name
+		}
+
+		return libs[i].Module < libs[j].Module
+	})
+
+	return
+}
+
+// findDrift returns every module required at different versions by libraries in chain
+func findDrift(chain []*library) (drifts []*drift) {
+	byModule := make(map[string]*drift)
+	for _, lib := range chain {
+		for _, req := range lib.Requires {
+			d, ok := byModule[req.Module]
+			if !ok {
+				d = &drift{Type: "drift", Module: req.Module, Versions: make(map[string][]string), consumers: make(map[string]*library)}
+				byModule[req.Module] = d
+			}
+
+			d.Versions[req.Version] = append(d.Versions[req.Version], lib.Name)
+			d.consumers[lib.Module] = lib
+			if len(d.Highest) == 0 || compareVersions(req.Version, d.Highest) > 0 {
+				d.Highest = req.Version
+			}
+		}
+	}
+
+	for _, d := range byModule {
+		if len(d.Versions) > 1 {
+			drifts = append(drifts, d)
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].Module < drifts[j].Module
+	})
+
+	return
+}
+
+// printDrift prints a consumer × version matrix for each drifting module
+// With -fix, every consumer is aligned on the highest version before running sync
+func printDrift(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	drifts := findDrift(chain)
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, d := range drifts {
+			encoder.Encode(d)
+		}
+	} else if len(drifts) == 0 {
+		fmt.Println("No version drift found!")
+	} else {
+		printDriftMatrix(drifts)
+	}
+
+	if !fixDrift || len(drifts) == 0 {
+		return
+	}
+
+	return alignDrift(drifts, options)
+}
+
+func printDriftMatrix(drifts []*drift) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, d := range drifts {
+		versions := d.SortedVersions()
+		fmt.Fprintf(w, "\n%s\t%s\n", d.Module, strings.Join(versions, "\t"))
+
+		for _, lib := range d.Consumers() {
+			req, _ := lib.Requirement(d.Module)
+			row := "  " + lib.Name
+			for _, version := range versions {
+				cell := ""
+				if version == req.Version {
+					cell = "x"
+				}
+
+				row += "\t" + cell
+			}
+
+			fmt.Fprintln(w, row)
+		}
+	}
+
+	w.Flush()
+	fmt.Printf("\n%d modules required at more than one version\n", len(drifts))
+}
+
+// alignDrift requires the highest version of each drifting module everywhere, then runs sync
+func alignDrift(drifts []*drift, options gomu.Options) (err error) {
+	// Refuse before any go.mod is edited, not once sync starts
+	options.Action = "sync"
+	if err = checkRemote(options); err != nil {
+		return
+	}
+
+	checkCycles(options)
+
+	for _, d := range drifts {
+		for _, lib := range d.Consumers() {
+			req, _ := lib.Requirement(d.Module)
+			if req.Version == d.Highest {
+				continue
+			}
+
+			target := d.Module + "@" + d.Highest
+			if dryRun {
+				fmt.Printf("%s: would run `go get %s`\n", lib.Name, target)
+				continue
+			}
+
+			warn(lib.Name + ": aligning " + target + "...")
+			if err = runCommand(lib, os.Stdout, "go", "get", target); err != nil {
+				return
+			}
+		}
+	}
+
+	if dryRun {
+		return
+	}
+
+	// Hand the aligned go.mod files to the normal sync logic
+	newMU(options).RunThen(printOutput)
+	return
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/hatchify/simply"
+)
+
+func TestDrift_ForkedConsumers(context *testing.T) {
+	chain := []*library{
+		{Name: "utils", Module: "github.com/hatchify/utils", Requires: []requirement{{Module: "github.com/hatchify/parg", Version: "v0.1.0"}}},
+		{Name: "utils", Module: "github.com/gomuserver/utils", Requires: []requirement{{Module: "github.com/hatchify/parg", Version: "v0.2.0"}}},
+	}
+
+	drifts := findDrift(chain)
+
+	test := simply.Target(len(drifts), context, "parg should drift")
+	result := test.Equals(1)
+	test.Validate(result)
+
+	var modules []string
+	for _, lib := range drifts[0].Consumers() {
+		modules = append(modules, lib.Module)
+	}
+
+	test = simply.Target(modules, context, "Both forks of utils should be consumers")
+	result = test.Equals([]string{"github.com/gomuserver/utils", "github.com/hatchify/utils"})
+	test.Validate(result)
+
+	test = simply.Target(drifts[0].Highest, context, "Highest should be v0.2.0")
+	result = test.Equals("v0.2.0")
+	test.Validate(result)
+}
+
+func TestDrift_AlignChecksRemote(context *testing.T) {
+	remoteName = "upstream"
+	defer func() { remoteName = defaultRemote }()
+
+	drifts := findDrift([]*library{
+		{Name: "utils", Module: "github.com/hatchify/utils", Dir: "/nonexistent/utils", Requires: []requirement{{Module: "github.com/hatchify/parg", Version: "v0.1.0"}}},
+		{Name: "app", Module: "github.com/hatchify/app", Dir: "/nonexistent/app", Requires: []requirement{{Module: "github.com/hatchify/parg", Version: "v0.2.0"}}},
+	})
+
+	err := alignDrift(drifts, gomu.Options{})
+
+	test := simply.Target(err != nil && strings.Contains(err.Error(), "upstream"), context, "Aligning should refuse another remote before running go get")
+	result := test.Equals(true)
+	test.Validate(result)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// execArgs holds the command following `--` for the exec action
+var execArgs []string
+
+// splitExecArgs separates everything after `--` so it is not parsed as gomu flags
+func splitExecArgs(args []string) (gomuArgs, cmdArgs []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+
+	return args, nil
+}
+
+// execTask runs execArgs within each library
+// A single argument is run by the shell, so pipes and globs work
+// With -dry-run the command is only printed
+func execTask(lib *library, w io.Writer) error {
+	fmt.Fprintf(w, "\n== %s ==\n", lib.Name)
+	if dryRun {
+		fmt.Fprintf(w, "would run `%s` in %s\n", strings.Join(execArgs, " "), lib.Dir)
+		return nil
+	}
+
+	if len(execArgs) == 1 {
+		return runCommand(lib, w, "sh", "-c", execArgs[0])
+	}
+
+	return runCommand(lib, w, execArgs[0], execArgs[1:]...)
+}
+
+// runExec runs a command in every library of the chain, in dependency order
+// With -parallel, libraries at the same level run concurrently
+func runExec(options gomu.Options) (err error) {
+	if len(execArgs) == 0 {
+		return errors.New("exec requires a command, usage: `gomu exec <libraries> -- <command>`")
+	}
+
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	var report *jsonReport
+	if outputJSON {
+		report = newJSONReport(options)
+	}
+
+	results := runLevels(chain, parallel, execTask)
+	if dryRun {
+		return
+	}
+
+	errs := resultErrors(results)
+	if report != nil {
+		finishParallelJSON(report, results, errs)
+		return
+	}
+
+	failures := failuresFrom(options.Action, errs, chain)
+
+	fmt.Printf("\n`%s` in %d libraries:\n", strings.Join(execArgs, " "), len(results))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, result := range results {
+		state := "pass"
+		if result.err != nil {
+			state = "FAIL"
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\n", result.lib.Name, state)
+	}
+
+	w.Flush()
+
+	if len(failures) > 0 {
+		fmt.Printf("\n%d of %d libraries failed\n", len(failures), len(results))
+		exitWithFailures(failures)
+	}
+
+	return
+}
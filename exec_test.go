@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestExec_SplitArgs(context *testing.T) {
+	gomuArgs, cmdArgs := splitExecArgs([]string{"exec", "-i", "hatchify", "--", "go", "vet", "-v", "--", "./..."})
+
+	test := simply.Target(gomuArgs, context, "gomu should only parse arguments before --")
+	result := test.Equals([]string{"exec", "-i", "hatchify"})
+	test.Validate(result)
+
+	test = simply.Target(cmdArgs, context, "Everything after the first -- belongs to the command")
+	result = test.Equals([]string{"go", "vet", "-v", "--", "./..."})
+	test.Validate(result)
+}
+
+func TestExec_Task(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	lib := &library{Name: "parg", Dir: filepath.Join(root, "parg")}
+	defer func() { execArgs = nil }()
+
+	var buf bytes.Buffer
+	execArgs = []string{"head -1 go.mod | tr a-z A-Z"}
+	err := execTask(lib, &buf)
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(buf.String(), context, "A single argument should run through the shell within the library")
+	result = test.Equals("\n== parg ==\nMODULE GITHUB.COM/HATCHIFY/PARG\n")
+	test.Validate(result)
+
+	execArgs = []string{"test", "-f", "go.sum"}
+	err = execTask(lib, &buf)
+
+	test = simply.Target(err != nil, context, "A failing command should return an error")
+	result = test.Equals(true)
+	test.Validate(result)
+}
+
+func TestExec_DryRun(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	lib := &library{Name: "parg", Dir: filepath.Join(root, "parg")}
+	dryRun = true
+	defer func() { execArgs, dryRun = nil, false }()
+
+	var buf bytes.Buffer
+	execArgs = []string{"rm", "go.mod"}
+	err := execTask(lib, &buf)
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(buf.String(), context, "Dry run should print the command")
+	result = test.Equals("\n== parg ==\nwould run `rm go.mod` in " + lib.Dir + "\n")
+	test.Validate(result)
+
+	_, err = os.Stat(filepath.Join(lib.Dir, "go.mod"))
+
+	test = simply.Target(err, context, "Dry run should not run the command")
+	result = test.Assert().Equals(nil)
+	test.Validate(result)
+}
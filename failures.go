@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// Exit codes
+const (
+	// exitSuccess means every library was processed without error
+	exitSuccess = 0
+	// exitFailure means one or more libraries failed for an uncategorized reason
+	exitFailure = 1
+	// exitUsage means the command line could not be parsed
+	exitUsage = 2
+	// exitGit means one or more git or network operations failed
+	exitGit = 3
+	// exitTest means one or more libraries failed their tests
+	exitTest = 4
+	// exitCycle means the dependency chain contains a cycle
+	exitCycle = 5
+)
+
+// Failure categories
+const (
+	categoryGit   = "git"
+	categoryMod   = "mod"
+	categoryTest  = "test"
+	categoryOther = "other"
+)
+
+// failure is a single error reported by a command
+type failure struct {
+	Library  string `json:"library"`
+	Category string `json:"category"`
+	Error    string `json:"error"`
+}
+
+// commandPattern finds the command quoted by runCommand errors, e.g. name: `go test ./...` failed
+var commandPattern = regexp.MustCompile("`([^`]+)` failed")
+
+// hostPattern matches module paths and urls, so host names like github.com are not read as keywords
+var hostPattern = regexp.MustCompile(`[\w.-]+\.[a-z]{2,}/[\w.@~/-]*`)
+
+var (
+	gitPattern = regexp.MustCompile(`\b(git|push|pull|fetch|checkout|merge|clone|rebase|stash)\b|could not read|unable to access|timed out|connection|network|permission denied|authentication failed`)
+	modPattern = regexp.MustCompile(`go\.mod|go\.sum|\bgo (get|mod)\b|unknown revision|invalid version|missing go\.sum entry`)
+)
+
+// categorize returns the failure category of an error message for the given command
+// The command quoted by runCommand decides first, then keywords outside of module paths
+func categorize(action, msg string) string {
+	if match := commandPattern.FindStringSubmatch(msg); match != nil {
+		fields := strings.Fields(match[1])
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "git":
+			return categoryGit
+		case len(fields) > 1 && fields[0] == "go" && fields[1] == "test":
+			return categoryTest
+		case len(fields) > 1 && fields[0] == "go" && (fields[1] == "mod" || fields[1] == "get"):
+			return categoryMod
+		}
+	}
+
+	lower := hostPattern.ReplaceAllString(strings.ToLower(msg), "")
+	switch {
+	case gitPattern.MatchString(lower):
+		return categoryGit
+	case modPattern.MatchString(lower):
+		return categoryMod
+	case action == "test":
+		return categoryTest
+	default:
+		return categoryOther
+	}
+}
+
+// failingLibrary returns the library an error message belongs to, or nil
+// Errors are prefixed by `name:` (see runCommand), or mention the full module path
+func failingLibrary(msg string, chain []*library) (match *library) {
+	for _, lib := range chain {
+		if strings.HasPrefix(msg, lib.Name+":") {
+			return lib
+		}
+	}
+
+	// Prefer the longest module path, so a/b/v2 wins over a/b
+	for _, lib := range chain {
+		if len(lib.Module) == 0 || (match != nil && len(match.Module) >= len(lib.Module)) {
+			continue
+		}
+
+		modulePattern := regexp.MustCompile(`(^|[^\w./~-])` + regexp.QuoteMeta(lib.Module) + `($|[^\w./~-])`)
+		if modulePattern.MatchString(msg) {
+			match = lib
+		}
+	}
+
+	return
+}
+
+// failuresFrom categorizes each error and attributes it to a library from chain when possible
+func failuresFrom(action string, errs []string, chain []*library) (failures []failure) {
+	for _, msg := range errs {
+		f := failure{Library: "-", Category: categorize(action, msg), Error: msg}
+		if lib := failingLibrary(msg, chain); lib != nil {
+			f.Library = lib.Name
+		}
+
+		failures = append(failures, f)
+	}
+
+	return
+}
+
+// exitCodeFor returns the exit code representing failures
+// Test failures take precedence over git failures, which take precedence over the rest
+func exitCodeFor(failures []failure) (code int) {
+	for _, f := range failures {
+		switch {
+		case f.Category == categoryTest:
+			return exitTest
+		case f.Category == categoryGit:
+			code = exitGit
+		case code == exitSuccess:
+			code = exitFailure
+		}
+	}
+
+	return
+}
+
+// errorStrings converts the errors collected by mod-utils to strings
+func errorStrings(mu *gomu.MU) (errs []string) {
+	errs = make([]string, 0, len(mu.Errors))
+	for _, err := range mu.Errors {
+		errs = append(errs, fmt.Sprint(err))
+	}
+
+	return
+}
+
+// printFailures prints a table of failures grouped by category
+func printFailures(failures []failure) {
+	grouped := make(map[string][]failure)
+	categories := make([]string, 0)
+	for _, f := range failures {
+		if _, ok := grouped[f.Category]; !ok {
+			categories = append(categories, f.Category)
+		}
+
+		grouped[f.Category] = append(grouped[f.Category], f)
+	}
+
+	sort.Strings(categories)
+
+	width := len("library")
+	for _, f := range failures {
+		if len(f.Library) > width {
+			width = len(f.Library)
+		}
+	}
+
+	for _, category := range categories {
+		com.Println(fmt.Sprintf("[%s] %d failed:", category, len(grouped[category])))
+		for _, f := range grouped[category] {
+			com.Println(fmt.Sprintf("  %-*s  %s", width, f.Library, f.Error))
+		}
+	}
+}
+
+// exitWithFailures exits with the code representing failures, if any
+func exitWithFailures(failures []failure) {
+	if code := exitCodeFor(failures); code != exitSuccess {
+		removeScope()
+		os.Exit(code)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestFailures_Categorize(context *testing.T) {
+	cases := []struct {
+		action, msg, expected string
+	}{
+		{"test", "svc-a: `go test ./...` failed: exit status 1", categoryTest},
+		{"test", "github.com/hatchify/svc-a: `go test ./...` failed: exit status 1", categoryTest},
+		{"sync", "parg: `git push origin master` failed: exit status 128", categoryGit},
+		{"sync", "parg: `go mod tidy` failed: exit status 1", categoryMod},
+		{"sync", "github.com/hatchify/parg: go.mod has post-v0 module path", categoryMod},
+		{"sync", "github.com/hatchify/parg: missing go.sum entry", categoryMod},
+		{"sync", "fatal: could not read Username for 'https://github.com'", categoryGit},
+		{"pull", "github.com/hatchify/branch-utils: unable to access remote", categoryGit},
+		{"sync", "github.com/hatchify/module-remote: something unexpected", categoryOther},
+		{"test", "github.com/hatchify/svc-a: build failed", categoryTest},
+	}
+
+	for _, c := range cases {
+		test := simply.Target(categorize(c.action, c.msg), context, c.msg+" should be categorized as "+c.expected)
+		result := test.Equals(c.expected)
+		test.Validate(result)
+	}
+}
+
+func TestFailures_From(context *testing.T) {
+	chain := []*library{
+		{Name: "utils", Module: "github.com/hatchify/utils"},
+		{Name: "mod-utils", Module: "github.com/gomuserver/mod-utils"},
+		{Name: "parg", Module: "github.com/hatchify/parg"},
+		{Name: "parg-v2", Module: "github.com/hatchify/parg/v2"},
+	}
+
+	errs := []string{
+		"mod-utils: `go test ./...` failed: exit status 1",
+		"error updating github.com/hatchify/parg/v2: unknown revision v2.0.1",
+		"github.com/hatchify/parg: go.mod malformed",
+		"github.com/hatchify/utilsx: not local",
+	}
+
+	failures := failuresFrom("sync", errs, chain)
+
+	expected := []string{"mod-utils", "parg-v2", "parg", "-"}
+	for i, f := range failures {
+		test := simply.Target(f.Library, context, errs[i]+" should be attributed to "+expected[i])
+		result := test.Equals(expected[i])
+		test.Validate(result)
+	}
+}
+
+func TestFailures_ExitCode(context *testing.T) {
+	cases := []struct {
+		categories []string
+		expected   int
+	}{
+		{nil, exitSuccess},
+		{[]string{categoryOther}, exitFailure},
+		{[]string{categoryMod}, exitFailure},
+		{[]string{categoryOther, categoryGit}, exitGit},
+		{[]string{categoryGit, categoryTest, categoryOther}, exitTest},
+	}
+
+	for _, c := range cases {
+		var failures []failure
+		for _, category := range c.categories {
+			failures = append(failures, failure{Library: "parg", Category: category})
+		}
+
+		test := simply.Target(exitCodeFor(failures), context, "Exit code should reflect the most specific category")
+		result := test.Equals(c.expected)
+		test.Validate(result)
+	}
+}
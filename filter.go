@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// ignoreFile lists patterns to exclude within an included directory
+const ignoreFile = ".gomuignore"
+
+// excludePatterns is set by the -exclude flag
+var excludePatterns []string
+
+// isRegexPattern returns true for patterns wrapped in slashes, e.g. /^svc-/
+func isRegexPattern(pattern string) bool {
+	return len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+}
+
+// isGlobPattern returns true for patterns using glob syntax, e.g. svc-*
+func isGlobPattern(pattern string) bool {
+	return !isRegexPattern(pattern) && strings.ContainsAny(pattern, "*?[")
+}
+
+// validatePatterns returns an error for the first malformed pattern
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		switch {
+		case isRegexPattern(pattern):
+			if _, err := regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+				return fmt.Errorf("invalid regex %s: %v", pattern, err)
+			}
+		case isGlobPattern(pattern):
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid glob %s: %v", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchPattern returns true if pattern names lib by directory name, module path or location
+// Patterns may be exact names, globs or /regexes/
+func matchPattern(pattern string, lib *library) bool {
+	switch {
+	case isRegexPattern(pattern):
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		return err == nil && (re.MatchString(lib.Name) || re.MatchString(lib.Module))
+	case isGlobPattern(pattern):
+		nameMatch, _ := path.Match(pattern, lib.Name)
+		moduleMatch, _ := path.Match(pattern, lib.Module)
+		return nameMatch || moduleMatch
+	default:
+		return pattern == lib.Name || pattern == lib.Module || samePath(pattern, lib.Dir)
+	}
+}
+
+// samePath returns true if both paths resolve to the same location
+func samePath(a, b string) bool {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false
+	}
+
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false
+	}
+
+	return absA == absB
+}
+
+// isExcludedDir returns true if dir was excluded as a whole
+func isExcludedDir(dir string) bool {
+	for _, pattern := range excludePatterns {
+		if !isRegexPattern(pattern) && !isGlobPattern(pattern) && samePath(pattern, dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExcluded returns true if lib matches -exclude or the ignore patterns of its directory
+func isExcluded(lib *library, ignored []string) bool {
+	for _, pattern := range excludePatterns {
+		if matchPattern(pattern, lib) {
+			return true
+		}
+	}
+
+	for _, pattern := range ignored {
+		if matchPattern(pattern, lib) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readIgnoreFile returns the patterns listed in dir/.gomuignore, one per line
+func readIgnoreFile(dir string) (patterns []string) {
+	f, err := os.Open(filepath.Join(dir, ignoreFile))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	return
+}
+
+// needsSelection returns true if mod-utils cannot select libraries from options by itself
+func needsSelection(options gomu.Options) bool {
+	if len(excludePatterns) > 0 || selectDependents || len(sinceRef) > 0 || maxDepth >= 0 {
+		return true
+	}
+
+	for _, filter := range options.FilterDependencies {
+		if isRegexPattern(filter) || isGlobPattern(filter) {
+			return true
+		}
+	}
+
+	for _, dir := range options.TargetDirectories {
+		if _, err := os.Stat(filepath.Join(dir, ignoreFile)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopeDir links to each library of the selection while mod-utils runs, empty otherwise
+var scopeDir string
+
+// resolveSelection points mod-utils at a scope holding exactly the selected chain
+// mod-utils walks down from every filter it is given, adding back dependencies that were
+// excluded, beyond -depth or not changed -since. Within the scope there is nothing else to add.
+func resolveSelection(options gomu.Options) (resolved gomu.Options, err error) {
+	resolved = options
+	if !needsSelection(options) {
+		return
+	}
+
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	if len(chain) == 0 {
+		return resolved, fmt.Errorf("no libraries match %s", strings.Join(options.FilterDependencies, " "))
+	}
+
+	if scopeDir, err = scopeChain(chain); err != nil {
+		return
+	}
+
+	resolved.TargetDirectories = []string{scopeDir}
+	resolved.FilterDependencies = make([]string, len(chain))
+	for i, lib := range chain {
+		resolved.FilterDependencies[i] = lib.Name
+	}
+
+	return
+}
+
+// scopeChain creates a temporary directory linking to each library of chain by name
+func scopeChain(chain []*library) (dir string, err error) {
+	if dir, err = ioutil.TempDir("", "gomu-scope"); err != nil {
+		return
+	}
+
+	linked := make(map[string]*library, len(chain))
+	for _, lib := range chain {
+		if other, ok := linked[lib.Name]; ok {
+			err = fmt.Errorf("%s and %s are both named %s, exclude one of them", other.Dir, lib.Dir, lib.Name)
+			break
+		}
+
+		linked[lib.Name] = lib
+		if err = os.Symlink(absPath(lib.Dir), filepath.Join(dir, lib.Name)); err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return
+}
+
+// removeScope deletes the scope created by resolveSelection, if any
+func removeScope() {
+	if len(scopeDir) > 0 {
+		os.RemoveAll(scopeDir)
+		scopeDir = ""
+	}
+}
+
+// newMU hands the resolved selection to mod-utils
+func newMU(options gomu.Options) *gomu.MU {
+	checkCycles(options)
+
+	options, err := resolveSelection(options)
+	if err != nil {
+		com.Errorln(err)
+		os.Exit(exitUsage)
+	}
+
+	return gomu.New(options)
+}
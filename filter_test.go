@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/hatchify/simply"
+)
+
+// scopeNames returns the libraries mod-utils can see once options are resolved
+func scopeNames(context *testing.T, options gomu.Options) (names []string) {
+	resolved, err := resolveSelection(options)
+	if err != nil {
+		context.Fatal(err)
+	}
+	defer removeScope()
+
+	test := simply.Target(resolved.TargetDirectories, context, "mod-utils should only be pointed at the scope")
+	result := test.Equals([]string{scopeDir})
+	test.Validate(result)
+
+	infos, err := ioutil.ReadDir(scopeDir)
+	if err != nil {
+		context.Fatal(err)
+	}
+
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+
+	test = simply.Target(len(resolved.FilterDependencies), context, "Every library of the scope should be named")
+	result = test.Equals(len(names))
+	test.Validate(result)
+
+	return
+}
+
+func TestFilter_ScopeExcludes(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	excludePatterns = []string{"scribe"}
+	defer func() { excludePatterns = nil }()
+
+	names := scopeNames(context, gomu.Options{TargetDirectories: []string{root}, FilterDependencies: []string{"mod-utils"}})
+
+	test := simply.Target(names, context, "Excluded scribe should not be added back as a dependency of mod-utils")
+	result := test.Equals([]string{"mod-utils"})
+	test.Validate(result)
+}
+
+func TestFilter_ScopeFollowsLinks(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	chain, _ := loadChain([]string{root}, []string{"scribe"}, false)
+	dir, err := scopeChain(chain)
+	if err != nil {
+		context.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	scoped, _ := loadChain([]string{dir}, nil, false)
+
+	test := simply.Target(len(scoped), context, "Scope should hold scribe and parg")
+	result := test.Equals(2)
+	test.Validate(result)
+
+	target, _ := filepath.EvalSymlinks(filepath.Join(root, "parg"))
+	test = simply.Target(scoped[0].Dir, context, "Linked libraries should report their real location")
+	result = test.Equals(target)
+	test.Validate(result)
+}
+
+func TestFilter_RunsLocally(context *testing.T) {
+	excludePatterns = []string{"scribe"}
+	defer func() { excludePatterns = nil }()
+
+	for action, expected := range map[string]bool{"test": false, "pull": false, "reset": false, "replace": true, "sync": false, "workflow": false} {
+		test := simply.Target(runsLocally(gomu.Options{Action: action}), context, "Only replace should leave mod-utils with a selection, not "+action)
+		result := test.Equals(expected)
+		test.Validate(result)
+	}
+
+	remoteName = "upstream"
+	defer func() { remoteName = defaultRemote }()
+
+	test := simply.Target(runsLocally(gomu.Options{Action: "pull"}), context, "pull should run locally from another remote")
+	result := test.Equals(true)
+	test.Validate(result)
+}
+
+func TestFilter_ScopeDependents(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	// scribe also depends on simply, which does not depend on parg
+	writeLibrary(context, root, "simply", "module github.com/hatchify/simply\n")
+	writeLibrary(context, root, "scribe", "module github.com/hatchify/scribe\n\nrequire (\n\tgithub.com/hatchify/parg v0.1.0\n\tgithub.com/hatchify/simply v0.0.18\n)\n")
+
+	selectDependents = true
+	defer func() { selectDependents = false }()
+
+	names := scopeNames(context, gomu.Options{TargetDirectories: []string{root}, FilterDependencies: []string{"parg"}})
+
+	test := simply.Target(names, context, "Only parg and its dependents should be handed to mod-utils, not simply")
+	result := test.Equals([]string{"mod-utils", "parg", "scribe"})
+	test.Validate(result)
+}
+
+func TestFilter_ScopeDepth(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	maxDepth = 1
+	defer func() { maxDepth = -1 }()
+
+	names := scopeNames(context, gomu.Options{TargetDirectories: []string{root}, FilterDependencies: []string{"mod-utils"}})
+
+	test := simply.Target(names, context, "parg is 2 steps from mod-utils, so it should not be handed to mod-utils")
+	result := test.Equals([]string{"mod-utils", "scribe"})
+	test.Validate(result)
+}
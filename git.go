@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// defaultRemote is the remote mod-utils always pushes to and pulls from
+const defaultRemote = "origin"
+
+// remoteName is the git remote gomu compares against, set by the `remote` config key
+var remoteName = defaultRemote
+
+// checkRemote refuses actions run by mod-utils, which always go through origin, when another remote is configured
+func checkRemote(options gomu.Options) error {
+	if remoteName == defaultRemote {
+		return nil
+	}
+
+	switch options.Action {
+	case "sync", "workflow":
+		return fmt.Errorf("%s pushes and pulls through %s, not the configured remote %s", options.Action, defaultRemote, remoteName)
+	}
+
+	return nil
+}
+
+// gitOutput runs git within dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (output string, err error) {
+	lib := gomu.LibraryFromPath(dir)
+	output, err = lib.File.CmdOutput("git", args...)
+	output = strings.TrimSpace(output)
+	return
+}
+
+// srcDir returns ~/go/src, where upgrade expects gomu and clone places every library
+func srcDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "go", "src")
+}
+
+// cloneDir returns where a module is cloned when it has no working copy, following the GOPATH layout under ~/go
+// Major version suffixes are dropped, as they live in the same repository
+func cloneDir(module string) string {
+	parts := strings.Split(module, "/")
+	if last := parts[len(parts)-1]; len(parts) > 3 && len(last) > 1 && last[0] == 'v' {
+		if _, err := strconv.Atoi(last[1:]); err == nil {
+			parts = parts[:len(parts)-1]
+		}
+	}
+
+	return filepath.Join(srcDir(), filepath.Join(parts...))
+}
+
+// cloneRepo clones url into lib.Dir
+func cloneRepo(lib *library, url string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(lib.Dir), 0755); err != nil {
+		return
+	}
+
+	parent := &library{Name: lib.Name, Dir: filepath.Dir(lib.Dir)}
+	return runCommand(parent, ioutil.Discard, "git", "clone", url, lib.Dir)
+}
+
+// branchExists checks for a branch locally and on the remote
+func branchExists(dir, branch string) (local, remote bool) {
+	_, err := gitOutput(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	local = err == nil
+
+	_, err = gitOutput(dir, "rev-parse", "--verify", "--quiet", "refs/remotes/"+remoteName+"/"+branch)
+	remote = err == nil
+	return
+}
+
+// commitsSince returns the number of commits on HEAD since ref
+func commitsSince(dir, ref string) (count int, err error) {
+	var output string
+	if output, err = gitOutput(dir, "rev-list", "--count", ref+"..HEAD"); err != nil {
+		return
+	}
+
+	return strconv.Atoi(output)
+}
+
+// nextVersion returns the tag that would follow latest
+// A non-empty setVersion always takes precedence
+// Tags which are not semver (vMAJOR.MINOR.PATCH) cannot be incremented
+func nextVersion(latest, setVersion string) (version string, ok bool) {
+	if len(setVersion) > 0 {
+		return setVersion, true
+	}
+
+	if len(latest) == 0 {
+		return "v0.0.1", true
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(latest, "v"), ".", 3)
+	if len(parts) != 3 {
+		return
+	}
+
+	// Drop any pre-release or build suffix from the patch version
+	patch := strings.FieldsFunc(parts[2], func(r rune) bool { return r == '-' || r == '+' })
+	if len(patch) == 0 {
+		return
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range []string{parts[0], parts[1], patch[0]} {
+		var err error
+		if numbers[i], err = strconv.Atoi(part); err != nil {
+			return
+		}
+	}
+
+	return fmt.Sprintf("v%d.%d.%d", numbers[0], numbers[1], numbers[2]+1), true
+}
+
+// compareVersions compares two semantic versions, returning -1, 0 or 1
+// Pre-release and pseudo versions sort before their release
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+
+	for i := 0; i < 3; i++ {
+		switch {
+		case aCore[i] < bCore[i]:
+			return -1
+		case aCore[i] > bCore[i]:
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case len(aPre) == 0:
+		return 1
+	case len(bPre) == 0:
+		return -1
+	}
+
+	return comparePrerelease(aPre, bPre)
+}
+
+// comparePrerelease compares dot separated pre-release identifiers as defined by semver
+// Numeric identifiers compare numerically and sort before alphanumeric ones,
+// and a shorter list of identifiers sorts first when all others are equal
+func comparePrerelease(a, b string) int {
+	aIDs, bIDs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		aNum, aErr := strconv.ParseUint(aIDs[i], 10, 64)
+		bNum, bErr := strconv.ParseUint(bIDs[i], 10, 64)
+
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+
+			return 1
+		case aErr == nil && bErr != nil:
+			return -1
+		case aErr != nil && bErr == nil:
+			return 1
+		case aErr != nil && aIDs[i] != bIDs[i]:
+			if aIDs[i] < bIDs[i] {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	switch {
+	case len(aIDs) < len(bIDs):
+		return -1
+	case len(aIDs) > len(bIDs):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func splitVersion(version string) (core [3]int, pre string) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexByte(version, '+'); i >= 0 {
+		version = version[:i]
+	}
+
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		version, pre = version[:i], version[i+1:]
+	}
+
+	for i, part := range strings.SplitN(version, ".", 3) {
+		core[i], _ = strconv.Atoi(part)
+	}
+
+	return
+}
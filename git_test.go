@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestNextVersion(context *testing.T) {
+	cases := map[string]string{
+		"v0.5.1":      "v0.5.2",
+		"v1.2.9-rc.1": "v1.2.10",
+		"":            "v0.0.1",
+	}
+
+	for latest, expected := range cases {
+		version, ok := nextVersion(latest, "")
+		test := simply.Target(version, context, latest+" should increment to "+expected)
+		result := test.Equals(expected)
+		test.Validate(result)
+
+		test = simply.Target(ok, context, latest+" should be incrementable")
+		result = test.Equals(true)
+		test.Validate(result)
+	}
+
+	for _, latest := range []string{"not-a-version", "release-2020", "v1.x.3"} {
+		_, ok := nextVersion(latest, "")
+		test := simply.Target(ok, context, latest+" should not be incrementable")
+		result := test.Equals(false)
+		test.Validate(result)
+	}
+
+	version, _ := nextVersion("v0.5.1", "v1.0.0")
+	test := simply.Target(version, context, "-set-version should take precedence")
+	result := test.Equals("v1.0.0")
+	test.Validate(result)
+}
+
+func TestCompareVersions(context *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"v0.5.1", "v0.5.1", 0},
+		{"v0.5.1", "v0.5.10", -1},
+		{"v1.0.0", "v0.9.9", 1},
+		{"v0.0.0-20200101000000-abcdef123456", "v0.0.1", -1},
+		{"v1.0.0-rc.1", "v1.0.0", -1},
+		{"v1.0.0-rc.9", "v1.0.0-rc.10", -1},
+		{"v1.0.0-rc.10", "v1.0.0-rc.9", 1},
+		{"v1.0.0-alpha", "v1.0.0-alpha.1", -1},
+		{"v1.0.0-alpha.1", "v1.0.0-alpha.beta", -1},
+		{"v1.0.0-beta.11", "v1.0.0-rc.1", -1},
+		{"v1.0.0-2", "v1.0.0-rc", -1},
+	}
+
+	for _, c := range cases {
+		test := simply.Target(compareVersions(c.a, c.b), context, c.a+" compared to "+c.b)
+		result := test.Equals(c.expected)
+		test.Validate(result)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// outputFormat is set by the -format flag
+var outputFormat string
+
+// graphEdge links a library to one of its local dependencies
+type graphEdge struct {
+	Name     string `json:"name"`
+	Module   string `json:"module"`
+	Version  string `json:"version,omitempty"`
+	Indirect bool   `json:"indirect,omitempty"`
+	// Source is the file the dependency was found in (go.mod or go.sum)
+	Source string `json:"source"`
+	// Replace is the target of an active replace directive, if any
+	Replace string `json:"replace,omitempty"`
+}
+
+// graphNode is a library along with its outgoing edges
+type graphNode struct {
+	Name   string      `json:"name"`
+	Module string      `json:"module"`
+	Path   string      `json:"path"`
+	Level  int         `json:"level"`
+	Depth  int         `json:"depth"`
+	Deps   []graphEdge `json:"deps"`
+}
+
+// Label describes the edge for dot and mermaid output
+func (edge graphEdge) Label() (label string) {
+	label = edge.Version
+	if len(label) == 0 {
+		label = edge.Source
+	}
+
+	if edge.Indirect {
+		label += " (indirect)"
+	}
+
+	if len(edge.Replace) > 0 {
+		label += " => " + edge.Replace
+	}
+
+	return
+}
+
+// graphNodes builds the adjacency list of chain
+func graphNodes(chain []*library) (nodes []graphNode) {
+	inChain := make(map[*library]bool, len(chain))
+	for _, lib := range chain {
+		inChain[lib] = true
+	}
+
+	for _, lib := range chain {
+		node := graphNode{Name: lib.Name, Module: lib.Module, Path: lib.Dir, Level: lib.Level, Depth: lib.Depth, Deps: []graphEdge{}}
+		for _, dep := range lib.Deps {
+			if !inChain[dep] {
+				continue
+			}
+
+			edge := graphEdge{Name: dep.Name, Module: dep.Module, Source: "go.sum"}
+			if req, ok := lib.Requirement(dep.Module); ok {
+				edge.Version = req.Version
+				edge.Indirect = req.Indirect
+				edge.Source = "go.mod"
+			}
+
+			if rep, ok := lib.Replacement(dep.Module); ok {
+				edge.Replace = rep.Target
+				if len(rep.TargetVersion) > 0 {
+					edge.Replace += " " + rep.TargetVersion
+				}
+			}
+
+			node.Deps = append(node.Deps, edge)
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return
+}
+
+// printGraph renders the dependency chain as dot, mermaid or json
+func printGraph(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	format := outputFormat
+	if outputJSON {
+		format = "json"
+	}
+
+	nodes := graphNodes(chain)
+	switch format {
+	case "", "dot":
+		fmt.Print(graphDot(nodes))
+	case "mermaid":
+		fmt.Print(graphMermaid(nodes))
+	case "json":
+		encoder := json.NewEncoder(jsonOut)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(nodes)
+	default:
+		err = fmt.Errorf("unsupported graph format %q, expected dot, mermaid or json", format)
+	}
+
+	return
+}
+
+// graphDot renders nodes as a dot digraph
+// Nodes are identified by module, as forks of a library share its name
+func graphDot(nodes []graphNode) string {
+	var sb strings.Builder
+	sb.WriteString("digraph gomu {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+
+	for _, node := range nodes {
+		fmt.Fprintf(&sb, "  %q [label=%q];\n", node.Module, node.Name)
+	}
+
+	for _, node := range nodes {
+		for _, edge := range node.Deps {
+			style := "solid"
+			if len(edge.Replace) > 0 {
+				style = "dashed"
+			} else if edge.Source == "go.sum" || edge.Indirect {
+				style = "dotted"
+			}
+
+			fmt.Fprintf(&sb, "  %q -> %q [label=%q, style=%s];\n", node.Module, edge.Module, edge.Label(), style)
+		}
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// graphMermaid renders nodes as a mermaid flowchart, identifying nodes by module
+func graphMermaid(nodes []graphNode) string {
+	ids := make(map[string]string, len(nodes))
+	for i, node := range nodes {
+		ids[node.Module] = fmt.Sprintf("n%d", i)
+	}
+
+	escape := strings.NewReplacer(`"`, "#quot;")
+
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	for _, node := range nodes {
+		fmt.Fprintf(&sb, "  %s[\"%s\"]\n", ids[node.Module], escape.Replace(node.Name))
+	}
+
+	for _, node := range nodes {
+		for _, edge := range node.Deps {
+			arrow := "-->"
+			if len(edge.Replace) > 0 || edge.Source == "go.sum" || edge.Indirect {
+				arrow = "-.->"
+			}
+
+			fmt.Fprintf(&sb, "  %s %s|\"%s\"| %s\n", ids[node.Module], arrow, escape.Replace(edge.Label()), ids[edge.Module])
+		}
+	}
+
+	return sb.String()
+}
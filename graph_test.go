@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func forkedChain() []*library {
+	utils := &library{Name: "utils", Module: "github.com/hatchify/utils"}
+	fork := &library{Name: "utils", Module: "github.com/gomuserver/utils"}
+	app := &library{Name: "app", Module: "github.com/hatchify/app", Deps: []*library{utils, fork}}
+	return []*library{utils, fork, app}
+}
+
+func TestGraph_DotForks(context *testing.T) {
+	dot := graphDot(graphNodes(forkedChain()))
+
+	test := simply.Target(strings.Count(dot, "[label=\"utils\"]"), context, "Both forks of utils should have their own node")
+	result := test.Equals(2)
+	test.Validate(result)
+
+	test = simply.Target(strings.Contains(dot, `"github.com/hatchify/app" -> "github.com/gomuserver/utils"`), context, "Edges should point at the module")
+	result = test.Equals(true)
+	test.Validate(result)
+}
+
+func TestGraph_MermaidForks(context *testing.T) {
+	mermaid := graphMermaid(graphNodes(forkedChain()))
+
+	test := simply.Target(strings.Contains(mermaid, "n2 -.->|\"go.sum\"| n0") && strings.Contains(mermaid, "n2 -.->|\"go.sum\"| n1"), context, "app should link to both forks of utils")
+	result := test.Equals(true)
+	test.Validate(result)
+}
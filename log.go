@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// logFrom, logAuthor and logGrep are set by the -from, -author and -grep flags
+var (
+	logFrom   string
+	logAuthor string
+	logGrep   string
+)
+
+// commit is a single entry of the aggregated log
+type commit struct {
+	Type    string    `json:"type"`
+	Library string    `json:"library"`
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+// logRange returns the git log arguments selecting commits since the given ref, date or duration
+// Without one, commits since the latest tag are selected
+// ok is false when from is a ref which does not exist in lib
+func logRange(lib *library, from string) (args []string, ok bool) {
+	if len(from) == 0 {
+		if tag := gomu.LibraryFromPath(lib.Dir).GetLatestTag(); len(tag) > 0 {
+			return []string{tag + "..HEAD"}, true
+		}
+
+		return []string{"HEAD"}, true
+	}
+
+	if t, ok := parseSinceDuration(from, time.Now()); ok {
+		return []string{"--since=" + t.Format(time.RFC3339), "HEAD"}, true
+	}
+
+	if _, err := time.Parse("2006-01-02", from); err == nil {
+		return []string{"--since=" + from, "HEAD"}, true
+	}
+
+	if _, err := gitOutput(lib.Dir, "rev-parse", "--verify", "--quiet", from+"^{commit}"); err == nil {
+		return []string{from + "..HEAD"}, true
+	}
+
+	return nil, false
+}
+
+// libraryLog returns the commits of lib since the given ref
+// skipped is true when the ref does not exist in lib
+func libraryLog(lib *library, from string) (commits []commit, skipped bool, err error) {
+	rangeArgs, ok := logRange(lib, from)
+	if !ok {
+		return nil, true, nil
+	}
+
+	args := []string{"log", "--format=%H%x1f%an%x1f%aI%x1f%s"}
+	if len(logAuthor) > 0 {
+		args = append(args, "--author="+logAuthor)
+	}
+
+	if len(logGrep) > 0 {
+		args = append(args, "--regexp-ignore-case", "--grep="+logGrep)
+	}
+
+	args = append(args, rangeArgs...)
+
+	var output string
+	if output, err = gitOutput(lib.Dir, args...); err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+
+		c := commit{Type: "commit", Library: lib.Name, Hash: fields[0], Author: fields[1], Subject: fields[3]}
+		c.Date, _ = time.Parse(time.RFC3339, fields[2])
+		commits = append(commits, c)
+	}
+
+	return
+}
+
+// printLog prints one chronological view of the commits across the chain
+func printLog(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	var (
+		commits []commit
+		skipped []string
+	)
+
+	for _, lib := range chain {
+		libCommits, skip, err := libraryLog(lib, logFrom)
+		if err != nil {
+			warn(lib.Name + ": " + err.Error())
+			continue
+		}
+
+		if skip {
+			skipped = append(skipped, lib.Name)
+			continue
+		}
+
+		commits = append(commits, libCommits...)
+	}
+
+	if len(skipped) > 0 {
+		warn(fmt.Sprintf("skipped %s, where %s does not exist", strings.Join(skipped, ", "), logFrom))
+	}
+
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Date.After(commits[j].Date)
+	})
+
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, c := range commits {
+			encoder.Encode(c)
+		}
+
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, c := range commits {
+		fmt.Fprintf(w, "%s\t%s\t%.8s\t%s\t%s\n", c.Date.Format("2006-01-02 15:04"), c.Library, c.Hash, c.Author, c.Subject)
+	}
+
+	w.Flush()
+	fmt.Printf("\n%d commits across %d libraries\n", len(commits), len(chain)-len(skipped))
+	return
+}
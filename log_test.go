@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestLog_Range(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	lib := &library{Name: "parg", Dir: filepath.Join(root, "parg")}
+	cases := map[string]string{
+		"":           "v0.1.0..HEAD",
+		"v0.1.0":     "v0.1.0..HEAD",
+		"2020-05-01": "--since=2020-05-01 HEAD",
+	}
+
+	for from, expected := range cases {
+		args, ok := logRange(lib, from)
+		test := simply.Target(strings.Join(args, " "), context, "Range from "+from+" should be "+expected)
+		result := test.Equals(expected)
+		test.Validate(result)
+
+		test = simply.Target(ok, context, "Range from "+from+" should resolve")
+		result = test.Equals(true)
+		test.Validate(result)
+	}
+
+	args, _ := logRange(lib, "2w")
+	test := simply.Target(strings.HasPrefix(args[0], "--since="), context, "Durations should use --since")
+	result := test.Equals(true)
+	test.Validate(result)
+
+	_, skipped, err := libraryLog(lib, "v0.5.0")
+
+	test = simply.Target(err, context, "Error should not exist")
+	result = test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(skipped, context, "Libraries without the ref should be skipped rather than searched by date")
+	result = test.Equals(true)
+	test.Validate(result)
+}
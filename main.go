@@ -16,6 +16,13 @@ func main() {
 	// Parse command line values, check supported functions, set defaults
 	gomu := fromArgs()
 
+	defer removeScope()
+	if outputJSON {
+		report := newJSONReport(gomu.Options)
+		gomu.RunThen(report.print)
+		return
+	}
+
 	gomu.RunThen(printOutput)
 }
 
@@ -25,8 +32,15 @@ func printOutput(mu *mod.MU) {
 			com.Println("")
 		}
 		com.Println(mu.Stats.Format())
-		com.Println("Quitting with errors:\n", mu.Errors)
+
+		chain, _ := loadChain(mu.Options.TargetDirectories, mu.Options.FilterDependencies, mu.Options.DirectImport)
+		failures := failuresFrom(mu.Options.Action, errorStrings(mu), chain)
+
+		com.Println("Quitting with errors:")
+		printFailures(failures)
 		com.Println("")
+
+		exitWithFailures(failures)
 	} else {
 		com.Println("\nAll clean!\n ")
 		com.Println(mu.Stats.Format())
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// staleRequirement is a require directive lagging behind the latest tag of its local working copy
+type staleRequirement struct {
+	Type       string `json:"type"`
+	Consumer   string `json:"consumer"`
+	Dependency string `json:"dependency"`
+	Module     string `json:"module"`
+	Current    string `json:"current"`
+	Latest     string `json:"latest"`
+}
+
+// latestTags looks up and caches the latest tag of each local working copy
+type latestTags map[*library]string
+
+// Of returns the latest tag of lib
+func (tags latestTags) Of(lib *library) string {
+	tag, ok := tags[lib]
+	if !ok {
+		tag = gomu.LibraryFromPath(lib.Dir).GetLatestTag()
+		tags[lib] = tag
+	}
+
+	return tag
+}
+
+// staleRequirements compares each requirement of chain against the latest tag of libs
+func staleRequirements(chain, libs []*library, tags latestTags) (stale []staleRequirement) {
+	byModule := make(map[string]*library, len(libs))
+	for _, lib := range libs {
+		byModule[lib.Module] = lib
+	}
+
+	for _, consumer := range chain {
+		for _, req := range consumer.Requires {
+			dep, ok := byModule[req.Module]
+			if !ok {
+				continue
+			}
+
+			latest := tags.Of(dep)
+			if len(latest) == 0 || compareVersions(req.Version, latest) >= 0 {
+				continue
+			}
+
+			stale = append(stale, staleRequirement{
+				Type:       "outdated",
+				Consumer:   consumer.Name,
+				Dependency: dep.Name,
+				Module:     dep.Module,
+				Current:    req.Version,
+				Latest:     latest,
+			})
+		}
+	}
+
+	return
+}
+
+// printOutdated reports requirements lagging behind local working copies,
+// grouped by dependency and by consumer
+func printOutdated(options gomu.Options) (err error) {
+	var libs []*library
+	if libs, err = discoverLibraries(options.TargetDirectories); err != nil {
+		return
+	}
+
+	chain := dependencyChain(libs, options.FilterDependencies, options.DirectImport)
+	stale := staleRequirements(chain, libs, make(latestTags))
+
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, s := range stale {
+			encoder.Encode(s)
+		}
+
+		return
+	}
+
+	if len(stale) == 0 {
+		fmt.Println("Every requirement is up to date!")
+		return
+	}
+
+	byDependency := make(map[string][]staleRequirement)
+	byConsumer := make(map[string][]staleRequirement)
+	for _, s := range stale {
+		byDependency[s.Dependency] = append(byDependency[s.Dependency], s)
+		byConsumer[s.Consumer] = append(byConsumer[s.Consumer], s)
+	}
+
+	fmt.Println("# Outdated by dependency")
+	for _, name := range sortedKeys(byDependency) {
+		group := byDependency[name]
+		fmt.Printf("\n%s (latest %s)\n", name, group[0].Latest)
+		for _, s := range group {
+			fmt.Printf("  %-30s %s\n", s.Consumer, s.Current)
+		}
+	}
+
+	fmt.Println("\n# Outdated by consumer")
+	for _, name := range sortedKeys(byConsumer) {
+		fmt.Printf("\n%s\n", name)
+		for _, s := range byConsumer[name] {
+			fmt.Printf("  %-30s %s -> %s\n", s.Dependency, s.Current, s.Latest)
+		}
+	}
+
+	fmt.Printf("\n%d outdated requirements\n", len(stale))
+	return
+}
+
+func sortedKeys(groups map[string][]staleRequirement) (keys []string) {
+	for key := range groups {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return
+}
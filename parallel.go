@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// parallel is set by the -parallel flag
+var parallel = 1
+
+// libraryTask runs against a single library, writing any output to w
+type libraryTask func(lib *library, w io.Writer) error
+
+// libraryResult is the outcome of a libraryTask
+type libraryResult struct {
+	lib    *library
+	output bytes.Buffer
+	err    error
+}
+
+// parseParallel validates the value of the -parallel flag
+func parseParallel(value string) (n int, err error) {
+	if len(value) == 0 {
+		return 1, nil
+	}
+
+	if n, err = strconv.Atoi(value); err != nil || n < 1 {
+		return 0, fmt.Errorf("-parallel expects a positive number, received %q", value)
+	}
+
+	return
+}
+
+// runLevels runs task for every library in chain, one level at a time
+// Up to workers libraries within the same level run concurrently
+// Output is buffered per library and printed in chain order once its level completes
+func runLevels(chain []*library, workers int, task libraryTask) (results []*libraryResult) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	for _, level := range chainLevels(chain) {
+		levelResults := make([]*libraryResult, len(level))
+		sem := make(chan struct{}, workers)
+
+		var wg sync.WaitGroup
+		for i, lib := range level {
+			result := &libraryResult{lib: lib}
+			levelResults[i] = result
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(result *libraryResult) {
+				defer wg.Done()
+				result.err = task(result.lib, &result.output)
+				<-sem
+			}(result)
+		}
+
+		wg.Wait()
+
+		for _, result := range levelResults {
+			os.Stdout.Write(result.output.Bytes())
+		}
+
+		results = append(results, levelResults...)
+	}
+
+	return
+}
+
+// runCommand runs name within the library directory, writing output to w
+func runCommand(lib *library, w io.Writer, name string, args ...string) (err error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = lib.Dir
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%s: `%s %s` failed: %v", lib.Name, name, strings.Join(args, " "), err)
+	}
+
+	return
+}
+
+// parallelTask returns the task used to run action concurrently over chain
+func parallelTask(options gomu.Options, chain []*library) (task libraryTask, ok bool) {
+	switch options.Action {
+	case "pull":
+		return func(lib *library, w io.Writer) error {
+			fmt.Fprintf(w, "\n== %s ==\n", lib.Name)
+			return pullLibrary(lib, w, options.Branch)
+		}, true
+
+	case "test":
+		return func(lib *library, w io.Writer) error {
+			fmt.Fprintf(w, "\n== %s ==\n", lib.Name)
+			return runCommand(lib, w, "go", "test", "./...")
+		}, true
+
+	case "replace":
+		return func(lib *library, w io.Writer) error {
+			fmt.Fprintf(w, "\n== %s ==\n", lib.Name)
+			return replaceLibrary(lib, chain, w)
+		}, true
+
+	case "reset":
+		return func(lib *library, w io.Writer) error {
+			fmt.Fprintf(w, "\n== %s ==\n", lib.Name)
+			return resetLibrary(lib, w)
+		}, true
+
+	case "sync":
+		// Tags are looked up once, as nothing is tagged before mod-utils publishes
+		tags := make(latestTags, len(chain))
+		for _, lib := range chain {
+			tags.Of(lib)
+		}
+
+		return func(lib *library, w io.Writer) error {
+			fmt.Fprintf(w, "\n== %s ==\n", lib.Name)
+			return prepareSync(lib, chain, tags, options.Branch, w)
+		}, true
+	}
+
+	return
+}
+
+// pullLibrary checks out (or creates) branch when provided, then pulls from its upstream
+// With a configured remote, the current branch is pulled from that remote instead
+func pullLibrary(lib *library, w io.Writer, branch string) (err error) {
+	if err = checkoutOrCreate(lib, w, branch); err != nil {
+		return
+	}
+
+	if remoteName != defaultRemote {
+		var current string
+		if current, err = gitOutput(lib.Dir, "rev-parse", "--abbrev-ref", "HEAD"); err != nil {
+			return
+		}
+
+		return runCommand(lib, w, "git", "pull", remoteName, current)
+	}
+
+	if _, err = gitOutput(lib.Dir, "rev-parse", "--abbrev-ref", "@{u}"); err != nil {
+		fmt.Fprintln(w, "No upstream branch. Skipping pull.")
+		return nil
+	}
+
+	return runCommand(lib, w, "git", "pull")
+}
+
+// checkoutOrCreate checks out branch when provided, creating it if it does not exist yet
+func checkoutOrCreate(lib *library, w io.Writer, branch string) error {
+	if len(branch) == 0 {
+		return nil
+	}
+
+	if local, remote := branchExists(lib.Dir, branch); local || remote {
+		return runCommand(lib, w, "git", "checkout", branch)
+	}
+
+	return runCommand(lib, w, "git", "checkout", "-b", branch)
+}
+
+// prepareSync runs the steps of sync which publish nothing, so they can run concurrently:
+// switching to branch, requiring the latest tag of each library of the chain and tidying go.mod
+// mod-utils then commits, tags and pushes serially, updating requirements on anything it tagged
+func prepareSync(lib *library, chain []*library, tags latestTags, branch string, w io.Writer) (err error) {
+	if err = checkoutOrCreate(lib, w, branch); err != nil {
+		return
+	}
+
+	for _, dep := range chain {
+		req, ok := lib.Requirement(dep.Module)
+		if !ok || dep == lib {
+			continue
+		}
+
+		if latest := tags[dep]; len(latest) > 0 && compareVersions(req.Version, latest) < 0 {
+			if err = runCommand(lib, w, "go", "get", dep.Module+"@"+latest); err != nil {
+				return
+			}
+		}
+	}
+
+	return runCommand(lib, w, "go", "mod", "tidy")
+}
+
+// runsLocally returns true if options.Action is run through runParallel rather than mod-utils
+// Selections run through mod-utils over the scope directory, except where mod-utils cannot honor them:
+// it always pulls from origin, and would point replacements at the links of the scope, removed once it is done
+func runsLocally(options gomu.Options) bool {
+	if parallel > 1 {
+		return true
+	}
+
+	switch options.Action {
+	case "pull":
+		return remoteName != defaultRemote
+	case "replace":
+		return needsSelection(options)
+	}
+
+	return false
+}
+
+// replaceLibrary points lib at the local copy of each library of the chain it depends on
+// Like mod-utils, libraries only referenced by go.sum are replaced too
+func replaceLibrary(lib *library, chain []*library, w io.Writer) (err error) {
+	deps := make(map[*library]bool, len(lib.Deps))
+	for _, dep := range lib.Deps {
+		deps[dep] = true
+	}
+
+	for _, dep := range chain {
+		if !deps[dep] || dep == lib {
+			continue
+		}
+
+		if err = runCommand(lib, w, "go", "mod", "edit", "-replace", dep.Module+"="+absPath(dep.Dir)); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// resetLibrary reverts go.mod and go.sum to their last committed version
+func resetLibrary(lib *library, w io.Writer) (err error) {
+	for _, name := range []string{"go.mod", "go.sum"} {
+		if tracked, _ := gitOutput(lib.Dir, "ls-files", name); len(tracked) == 0 {
+			continue
+		}
+
+		if err = runCommand(lib, w, "git", "checkout", "HEAD", "--", name); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// runParallel runs options.Action across the chain with -parallel workers, then exits
+// sync only runs the steps which publish nothing, then returns so mod-utils publishes serially
+// Actions which cannot run concurrently, like workflow, are left to the serial run
+func runParallel(options gomu.Options) {
+	if _, ok := parallelTask(options, nil); !ok {
+		warn("-parallel does not apply to " + options.Action + ", running serially")
+		return
+	}
+
+	chain, err := loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport)
+	if err != nil {
+		exitWithError(err.Error())
+	}
+
+	task, _ := parallelTask(options, chain)
+	if options.Action == "sync" {
+		checkCycles(options)
+		prepareParallel(options, chain, task)
+		return
+	}
+
+	var report *jsonReport
+	if outputJSON {
+		report = newJSONReport(options)
+	}
+
+	results := runLevels(chain, parallel, task)
+
+	errs := resultErrors(results)
+	if report != nil {
+		finishParallelJSON(report, results, errs)
+		os.Exit(exitSuccess)
+	}
+
+	failures := failuresFrom(options.Action, errs, chain)
+	if options.LogLevel != com.NAMEONLY {
+		com.Println(fmt.Sprintf("\nRan %s on %d libraries (%d at a time)", options.Action, len(results), parallel))
+		if len(failures) > 0 {
+			com.Println("Quitting with errors:")
+			printFailures(failures)
+		} else {
+			com.Println("All clean!")
+		}
+	}
+
+	exitWithFailures(failures)
+	os.Exit(exitSuccess)
+}
+
+// prepareParallel runs the non-publishing steps of options.Action across the chain
+// Failures exit before anything is published
+func prepareParallel(options gomu.Options, chain []*library, task libraryTask) {
+	results := runLevels(chain, parallel, task)
+	failures := failuresFrom(options.Action, resultErrors(results), chain)
+	if len(failures) > 0 {
+		if options.LogLevel != com.NAMEONLY && !outputJSON {
+			com.Println("Quitting with errors before publishing:")
+			printFailures(failures)
+		}
+
+		exitWithFailures(failures)
+	}
+
+	warn(fmt.Sprintf("Prepared %s on %d libraries (%d at a time), publishing serially through mod-utils", options.Action, len(results), parallel))
+}
+
+// resultErrors returns the error messages of failed results
+func resultErrors(results []*libraryResult) (errs []string) {
+	errs = make([]string, 0)
+	for _, result := range results {
+		if result.err != nil {
+			errs = append(errs, result.err.Error())
+		}
+	}
+
+	return
+}
+
+// finishParallelJSON writes the json report of a command run through runLevels
+func finishParallelJSON(report *jsonReport, results []*libraryResult, errs []string) {
+	summary := fmt.Sprintf("%d libraries, %d failed", len(results), len(errs))
+	report.finish(errs, map[string]int{"libraries": len(results), "failed": len(errs)}, summary)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/hatchify/simply"
+)
+
+func TestParallel_RunLevels(context *testing.T) {
+	// a and b are independent, c requires both
+	a := &library{Name: "a", Module: "a"}
+	b := &library{Name: "b", Module: "b"}
+	c := &library{Name: "c", Module: "c", Deps: []*library{a, b}}
+	chain := sortChain([]*library{c, b, a})
+
+	stdout := os.Stdout
+	f, err := ioutil.TempFile("", "gomu-levels")
+	if err != nil {
+		context.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+
+	var (
+		mux      sync.Mutex
+		started  []string
+		finished = make(map[string]bool)
+	)
+
+	results := runLevels(chain, 2, func(lib *library, w io.Writer) error {
+		mux.Lock()
+		started = append(started, lib.Name)
+		if lib.Name == "c" && !(finished["a"] && finished["b"]) {
+			mux.Unlock()
+			return fmt.Errorf("c started before its dependencies finished")
+		}
+		mux.Unlock()
+
+		// a finishes last within its level, and writes in two parts
+		fmt.Fprintf(w, "%s: start\n", lib.Name)
+		if lib.Name == "a" {
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		fmt.Fprintf(w, "%s: done\n", lib.Name)
+
+		mux.Lock()
+		finished[lib.Name] = true
+		mux.Unlock()
+		return nil
+	})
+
+	os.Stdout = stdout
+	output, _ := ioutil.ReadFile(f.Name())
+
+	test := simply.Target(resultErrors(results), context, "Levels should run in dependency order")
+	result := test.Equals([]string{})
+	test.Validate(result)
+
+	test = simply.Target(string(output), context, "Output should be buffered per library and printed in chain order")
+	result = test.Equals("a: start\na: done\nb: start\nb: done\nc: start\nc: done\n")
+	test.Validate(result)
+
+	test = simply.Target(started[len(started)-1], context, "The last level should start last")
+	result = test.Equals("c")
+	test.Validate(result)
+}
+
+func TestParallel_PrepareSync(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "parg")
+	lib := &library{Name: "parg", Module: "github.com/hatchify/parg", Dir: dir}
+
+	var buf bytes.Buffer
+	err := prepareSync(lib, []*library{lib}, latestTags{lib: "v0.1.0"}, "feature/deps", &buf)
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	branch, _ := gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+
+	test = simply.Target(branch, context, "Preparing should switch to the sync branch")
+	result = test.Equals("feature/deps")
+	test.Validate(result)
+
+	parallel = 2
+	defer func() { parallel = 1 }()
+
+	test = simply.Target(runsLocally(gomu.Options{Action: "sync"}), context, "sync should be prepared locally with -parallel")
+	result = test.Equals(true)
+	test.Validate(result)
+}
+
+func TestParallel_ReplaceSums(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	// scribe requires parg and only references simply through go.sum, which mod-utils replaces too
+	writeLibrary(context, root, "simply", "module github.com/hatchify/simply\n")
+	sum := "github.com/hatchify/simply v0.0.18 h1:abc=\ngithub.com/hatchify/simply v0.0.18/go.mod h1:def=\n"
+	if err := ioutil.WriteFile(filepath.Join(root, "scribe", "go.sum"), []byte(sum), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	chain, _ := loadChain([]string{root}, []string{"scribe"}, false)
+
+	var scribe *library
+	for _, lib := range chain {
+		if lib.Name == "scribe" {
+			scribe = lib
+		}
+	}
+
+	err := replaceLibrary(scribe, chain, ioutil.Discard)
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	modFile, _ := ioutil.ReadFile(filepath.Join(root, "scribe", "go.mod"))
+	for _, name := range []string{"parg", "simply"} {
+		test = simply.Target(strings.Contains(string(modFile), "github.com/hatchify/"+name+" => "+filepath.Join(root, name)), context, name+" should be replaced by its local copy")
+		result = test.Equals(true)
+		test.Validate(result)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// dryRun is set by the -dry-run flag
+var dryRun bool
+
+// planRecord lists the steps a command would take for a single library
+type planRecord struct {
+	Type   string   `json:"type"`
+	Name   string   `json:"name"`
+	Path   string   `json:"path"`
+	Module string   `json:"module"`
+	Branch string   `json:"branch"`
+	Steps  []string `json:"steps"`
+}
+
+// printPlan walks the dependency chain and prints what options.Action would do
+// No working copy is modified
+func printPlan(options gomu.Options) (err error) {
+	switch options.Action {
+	case "sync", "workflow", "pull":
+	default:
+		return fmt.Errorf("-dry-run is not supported for %s", options.Action)
+	}
+
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	inChain := make(map[string]*library, len(chain))
+	for _, lib := range chain {
+		inChain[lib.Module] = lib
+	}
+
+	// Versions each library will be at once the command has processed it
+	versions := make(map[*library]string, len(chain))
+
+	var encoder *json.Encoder
+	if outputJSON {
+		encoder = json.NewEncoder(jsonOut)
+	}
+
+	for _, lib := range chain {
+		record := planRecord{Type: "plan", Name: lib.Name, Path: lib.Dir, Module: lib.Module}
+		record.Steps, versions[lib], record.Branch = planLibrary(lib, options, inChain, versions)
+
+		if encoder != nil {
+			encoder.Encode(record)
+			continue
+		}
+
+		fmt.Printf("\n%s (%s) on %s\n", lib.Name, lib.Dir, record.Branch)
+		for _, step := range record.Steps {
+			fmt.Println("  - " + step)
+		}
+	}
+
+	if encoder == nil {
+		fmt.Printf("\nDry run complete: %d libraries, nothing was changed.\n", len(chain))
+	}
+
+	return
+}
+
+func planLibrary(lib *library, options gomu.Options, inChain map[string]*library, versions map[*library]string) (steps []string, version, branch string) {
+	tool := gomu.LibraryFromPath(lib.Dir)
+	latest := tool.GetLatestTag()
+	version = latest
+
+	current, _ := tool.File.CurrentBranch()
+	current = strings.TrimSpace(current)
+	branch = current
+	changed := tool.File.HasChanges()
+	if changed {
+		steps = append(steps, "include uncommitted local changes")
+	}
+
+	if len(options.Branch) > 0 && options.Branch != current {
+		branch = options.Branch
+		switch local, remote := branchExists(lib.Dir, options.Branch); {
+		case local:
+			steps = append(steps, "checkout branch "+options.Branch)
+		case remote:
+			steps = append(steps, "checkout branch "+options.Branch+" from "+remoteName)
+		default:
+			steps = append(steps, "create branch "+options.Branch)
+		}
+	}
+
+	switch options.Action {
+	case "pull":
+		steps = append(steps, "pull "+remoteName+" "+branch)
+
+	case "sync":
+		for _, req := range lib.Requires {
+			dep, ok := inChain[req.Module]
+			if !ok || dep == lib {
+				continue
+			}
+
+			if want := versions[dep]; len(want) > 0 && want != req.Version {
+				steps = append(steps, fmt.Sprintf("update go.mod: %s %s -> %s", req.Module, req.Version, want))
+				changed = true
+			}
+		}
+
+	case "workflow":
+		if len(options.SourcePath) == 0 {
+			steps = append(steps, "fail: -source is required")
+			break
+		}
+
+		steps = append(steps, "add .github/workflows/"+filepath.Base(options.SourcePath)+" from "+options.SourcePath)
+		changed = true
+	}
+
+	if options.Action == "pull" {
+		return
+	}
+
+	committed := options.Commit && changed
+	if committed {
+		msg := options.CommitMessage
+		if len(msg) == 0 {
+			msg = "(default message)"
+		}
+
+		steps = append(steps, "commit all changes: "+msg)
+		steps = append(steps, "push "+remoteName+" "+branch)
+	}
+
+	if options.Tag {
+		count := 0
+		if len(latest) > 0 {
+			count, _ = commitsSince(lib.Dir, latest)
+		}
+
+		switch {
+		case len(latest) == 0 && len(options.SetVersion) == 0:
+			steps = append(steps, "skip tag: no previous tag")
+		case count > 0 || committed || len(options.SetVersion) > 0:
+			next, ok := nextVersion(latest, options.SetVersion)
+			if !ok {
+				steps = append(steps, "skip tag: cannot increment "+latest)
+				break
+			}
+
+			version = next
+			steps = append(steps, "tag "+version+" and push tag")
+		}
+	}
+
+	if options.PullRequest {
+		if branch == "master" || branch == "main" {
+			steps = append(steps, "skip pull request: on "+branch)
+		} else {
+			steps = append(steps, "open pull request from "+branch)
+		}
+	}
+
+	if len(steps) == 0 {
+		steps = append(steps, "nothing to do")
+	}
+
+	return
+}
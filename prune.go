@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// pruneRemotes is set by the -remotes flag
+var pruneRemotes bool
+
+// staleBranch is a branch prune-branches would delete
+type staleBranch struct {
+	Name string `json:"name"`
+	// Reason is merged or gone
+	Reason string `json:"reason"`
+	Remote bool   `json:"remote,omitempty"`
+}
+
+// pruneRecord lists the stale branches of a library
+type pruneRecord struct {
+	Type     string        `json:"type"`
+	Library  string        `json:"library"`
+	Branches []staleBranch `json:"branches"`
+	Errors   []string      `json:"errors,omitempty"`
+
+	lib *library
+}
+
+// protectedBranch returns true for branches which are never pruned
+func protectedBranch(branch, current string) bool {
+	switch branch {
+	case current, defaultBranch, "master", "main", "HEAD":
+		return true
+	}
+
+	return false
+}
+
+func gitLines(dir string, args ...string) (lines []string) {
+	output, err := gitOutput(dir, args...)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	return
+}
+
+// staleBranches returns the branches of lib merged into the default branch, or whose upstream is gone
+func staleBranches(lib *library) (branches []staleBranch) {
+	// Update remote branches so deleted upstreams show as gone
+	gitOutput(lib.Dir, "fetch", "--prune", remoteName)
+
+	current, _ := gitOutput(lib.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+
+	base := defaultBranch
+	if local, _ := branchExists(lib.Dir, base); !local {
+		base = remoteName + "/" + defaultBranch
+	}
+
+	seen := make(map[string]bool)
+	for _, branch := range gitLines(lib.Dir, "branch", "--format=%(refname:short)", "--merged", base) {
+		if !protectedBranch(branch, current) {
+			seen[branch] = true
+			branches = append(branches, staleBranch{Name: branch, Reason: "merged"})
+		}
+	}
+
+	for _, line := range gitLines(lib.Dir, "for-each-ref", "--format=%(refname:short) %(upstream:track)", "refs/heads") {
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) == 2 && fields[1] == "[gone]" && !seen[fields[0]] && !protectedBranch(fields[0], current) {
+			branches = append(branches, staleBranch{Name: fields[0], Reason: "gone"})
+		}
+	}
+
+	if !pruneRemotes {
+		return
+	}
+
+	prefix := remoteName + "/"
+	for _, branch := range gitLines(lib.Dir, "branch", "-r", "--format=%(refname:short)", "--merged", prefix+defaultBranch) {
+		if !strings.HasPrefix(branch, prefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(branch, prefix)
+		if !protectedBranch(name, current) && name != remoteName {
+			branches = append(branches, staleBranch{Name: name, Reason: "merged", Remote: true})
+		}
+	}
+
+	return
+}
+
+// deleteBranch removes a stale branch, locally or from the remote
+func deleteBranch(lib *library, branch staleBranch) error {
+	switch {
+	case branch.Remote:
+		return runCommand(lib, ioutil.Discard, "git", "push", remoteName, "--delete", branch.Name)
+	case branch.Reason == "gone":
+		// Commits which never reached a remote would be lost, so only `git branch -d` may delete them
+		// Squash merged branches end up here too once their remote branch is deleted, and are reported instead
+		if unpushed, _ := gitOutput(lib.Dir, "rev-list", "--count", branch.Name, "--not", "--remotes"); unpushed != "0" {
+			if err := runCommand(lib, ioutil.Discard, "git", "branch", "-d", branch.Name); err != nil {
+				return fmt.Errorf("%v, %s has commits on no remote", err, branch.Name)
+			}
+
+			return nil
+		}
+
+		return runCommand(lib, ioutil.Discard, "git", "branch", "-D", branch.Name)
+	default:
+		// `git branch -d` checks merges against HEAD rather than the default branch, which staleBranches already checked
+		return runCommand(lib, ioutil.Discard, "git", "branch", "-D", branch.Name)
+	}
+}
+
+// confirm asks the user a yes/no question on stdin
+func confirm(question string) bool {
+	fmt.Fprint(os.Stderr, question+" [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+
+	return false
+}
+
+// runPruneBranches lists stale branches across the chain and deletes them once confirmed
+// -dry-run only lists them, and -name-only skips confirmation
+func runPruneBranches(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	var (
+		records []*pruneRecord
+		total   int
+	)
+
+	for _, lib := range chain {
+		record := &pruneRecord{Type: "prune", Library: lib.Name, Branches: staleBranches(lib), lib: lib}
+		if len(record.Branches) == 0 {
+			continue
+		}
+
+		total += len(record.Branches)
+		records = append(records, record)
+
+		// Listed before confirming, on stderr with -json
+		fmt.Printf("\n%s\n", lib.Name)
+		for _, branch := range record.Branches {
+			name := branch.Name
+			if branch.Remote {
+				name = remoteName + "/" + name
+			}
+
+			fmt.Printf("  %-40s %s\n", name, branch.Reason)
+		}
+	}
+
+	if total == 0 {
+		if !outputJSON {
+			fmt.Println("No stale branches!")
+		}
+
+		return
+	}
+
+	if dryRun || (options.LogLevel != com.NAMEONLY && !confirm(fmt.Sprintf("\nDelete %d branches?", total))) {
+		printPruneJSON(records)
+		return
+	}
+
+	var failures []failure
+	for _, record := range records {
+		for _, branch := range record.Branches {
+			if err := deleteBranch(record.lib, branch); err != nil {
+				record.Errors = append(record.Errors, err.Error())
+				failures = append(failures, failure{Library: record.Library, Category: categoryGit, Error: err.Error()})
+			}
+		}
+	}
+
+	printPruneJSON(records)
+	if !outputJSON {
+		fmt.Printf("\nDeleted %d of %d branches\n", total-len(failures), total)
+		if len(failures) > 0 {
+			printFailures(failures)
+		}
+	}
+
+	exitWithFailures(failures)
+	return
+}
+
+func printPruneJSON(records []*pruneRecord) {
+	if !outputJSON {
+		return
+	}
+
+	encoder := json.NewEncoder(jsonOut)
+	for _, record := range records {
+		encoder.Encode(record)
+	}
+}
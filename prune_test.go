@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestPrune_MergedIntoDefaultBranch(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "parg")
+	testGit(context, dir, "checkout", "-q", "-B", "master")
+	testGit(context, dir, "checkout", "-q", "-b", "develop")
+	testGit(context, dir, "checkout", "-q", "-b", "feature/done", "master")
+	if err := ioutil.WriteFile(filepath.Join(dir, "done.go"), []byte("package parg\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	testGit(context, dir, "add", "-A")
+	testGit(context, dir, "commit", "-q", "-m", "Add done.go")
+	testGit(context, dir, "checkout", "-q", "master")
+	testGit(context, dir, "merge", "-q", "feature/done")
+
+	// develop does not contain feature/done, so `git branch -d` would refuse from here
+	testGit(context, dir, "checkout", "-q", "develop")
+
+	lib := &library{Name: "parg", Dir: dir}
+	branches := staleBranches(lib)
+
+	test := simply.Target(branches, context, "feature/done is merged into master, develop is current")
+	result := test.Equals([]staleBranch{{Name: "feature/done", Reason: "merged"}})
+	test.Validate(result)
+
+	err := deleteBranch(lib, branches[0])
+
+	test = simply.Target(err, context, "Branches merged into the default branch should delete from any branch")
+	result = test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(protectedBranch("develop", "develop"), context, "The current branch should be protected")
+	result = test.Equals(true)
+	test.Validate(result)
+}
+
+func TestPrune_GoneWithUnpushedCommits(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	testGit(context, root, "clone", "-q", "--bare", filepath.Join(root, "parg"), "parg.git")
+	testGit(context, root, "clone", "-q", "parg.git", "clone")
+
+	dir := filepath.Join(root, "clone")
+	testGit(context, dir, "checkout", "-q", "-b", "feature/gone")
+	testGit(context, dir, "push", "-q", "-u", "origin", "feature/gone")
+	if err := ioutil.WriteFile(filepath.Join(dir, "unpushed.go"), []byte("package parg\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	testGit(context, dir, "add", "-A")
+	testGit(context, dir, "commit", "-q", "-m", "Add unpushed.go")
+	testGit(context, dir, "checkout", "-q", "-")
+	testGit(context, dir, "push", "-q", "origin", "--delete", "feature/gone")
+
+	lib := &library{Name: "parg", Dir: dir}
+	branches := staleBranches(lib)
+
+	test := simply.Target(branches, context, "feature/gone should be gone")
+	result := test.Equals([]staleBranch{{Name: "feature/gone", Reason: "gone"}})
+	test.Validate(result)
+
+	err := deleteBranch(lib, branches[0])
+
+	test = simply.Target(err != nil, context, "A gone branch with unpushed commits should fail to delete")
+	result = test.Equals(true)
+	test.Validate(result)
+
+	local, _ := branchExists(dir, "feature/gone")
+
+	test = simply.Target(local, context, "The unpushed commit should be kept")
+	result = test.Equals(true)
+	test.Validate(result)
+}
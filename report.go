@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// outputJSON is set by the -json flag
+var outputJSON bool
+
+// jsonOut is where json records are written
+// Human readable output is redirected to stderr while -json is set
+var jsonOut = os.Stdout
+
+// libraryRecord is the json record emitted for each library in the chain
+type libraryRecord struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Module string `json:"module"`
+	Branch string `json:"branch"`
+	// Depth is the number of steps from the nearest selected library
+	Depth int `json:"depth"`
+	// OldVersion and NewVersion are the versions of this library required by the go.mod files of the chain,
+	// before and after the command ran, empty when no library in the chain requires it
+	OldVersion string `json:"oldVersion"`
+	NewVersion string `json:"newVersion"`
+	// Tag is the tag created by the command, if any
+	Tag string `json:"tag,omitempty"`
+	// Action is derived by comparing the library before and after the command ran (see deriveAction)
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Changes are the edits made to the go.mod of this library
+	Changes []modChange `json:"changes,omitempty"`
+
+	before *library
+	tag    string
+	head   string
+	dirty  bool
+}
+
+// summaryRecord is the final json record emitted after the command completes
+type summaryRecord struct {
+	Type     string      `json:"type"`
+	Command  string      `json:"command"`
+	Success  bool        `json:"success"`
+	ExitCode int         `json:"exitCode"`
+	Stats    interface{} `json:"stats"`
+	Summary  string      `json:"summary"`
+	Errors   []string    `json:"errors"`
+	Failures []failure   `json:"failures"`
+}
+
+// jsonReport collects library state before a command runs so it can be compared afterwards
+type jsonReport struct {
+	command string
+	records []*libraryRecord
+}
+
+func newJSONReport(options gomu.Options) (report *jsonReport) {
+	report = &jsonReport{command: options.Action}
+
+	chain, err := loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport)
+	if err != nil {
+		return
+	}
+
+	for _, lib := range chain {
+		record := &libraryRecord{
+			Type:   "library",
+			Name:   lib.Name,
+			Path:   lib.Dir,
+			Module: lib.Module,
+			Depth:  lib.Depth,
+			before: lib,
+		}
+
+		record.OldVersion = requiredVersion(lib.Module, chain)
+		record.tag, record.head, record.dirty = libraryState(lib.Dir)
+		report.records = append(report.records, record)
+	}
+
+	return
+}
+
+// libraryState returns the latest tag, head commit and dirty flag of a working copy
+func libraryState(dir string) (tag, head string, dirty bool) {
+	lib := gomu.LibraryFromPath(dir)
+	tag = lib.GetLatestTag()
+	head, _ = lib.File.CmdOutput("git", "rev-parse", "HEAD")
+	head = strings.TrimSpace(head)
+	dirty = lib.File.HasChanges()
+	return
+}
+
+// requiredVersion returns the highest version of module required within chain
+func requiredVersion(module string, chain []*library) (version string) {
+	for _, lib := range chain {
+		if req, ok := lib.Requirement(module); ok && (len(version) == 0 || compareVersions(req.Version, version) > 0) {
+			version = req.Version
+		}
+	}
+
+	return
+}
+
+// deriveAction describes what command did to a library by comparing its state before and after
+// mod-utils does not report what it did per library, so this is inferred rather than reported
+func deriveAction(command string, record *libraryRecord, head string, dirty bool) string {
+	switch {
+	case len(record.Error) > 0:
+		return "failed"
+	case command == "list":
+		return "listed"
+	case command == "test":
+		return "tested"
+	case command == "exec":
+		return "ran"
+	case len(record.Tag) > 0:
+		return "tagged"
+	case record.head != head && command == "pull":
+		return "pulled"
+	case record.head != head:
+		return "committed"
+	case len(record.Changes) == 0 && record.dirty == dirty:
+		return "unchanged"
+	case command == "replace":
+		return "replaced"
+	case command == "reset":
+		return "reset"
+	default:
+		return "modified"
+	}
+}
+
+// print writes the report once mod-utils completes
+func (report *jsonReport) print(mu *gomu.MU) {
+	report.finish(errorStrings(mu), mu.Stats, mu.Stats.Format())
+}
+
+// finish writes a record per library, then the summary, and exits with the failures, if any
+func (report *jsonReport) finish(errs []string, stats interface{}, summary string) {
+	encoder := json.NewEncoder(jsonOut)
+
+	before := make([]*library, len(report.records))
+	after := make([]*library, 0, len(report.records))
+	for i, record := range report.records {
+		before[i] = record.before
+		if lib, err := libraryFromDir(record.Path); err == nil {
+			after = append(after, lib)
+		}
+	}
+
+	failures := failuresFrom(report.command, errs, before)
+
+	for _, record := range report.records {
+		lib := gomu.LibraryFromPath(record.Path)
+		record.Branch, _ = lib.File.CurrentBranch()
+		record.Branch = strings.TrimSpace(record.Branch)
+
+		tag, head, dirty := record.tag, record.head, record.dirty
+		record.tag, record.head, record.dirty = libraryState(record.Path)
+		if record.tag != tag {
+			record.Tag = record.tag
+		}
+
+		record.NewVersion = requiredVersion(record.Module, after)
+		if current, err := libraryFromDir(record.Path); err == nil {
+			record.Changes = diffMod(record.before, current)
+		}
+
+		for _, f := range failures {
+			if f.Library == record.Name {
+				record.Error = f.Error
+				record.Category = f.Category
+				break
+			}
+		}
+
+		record.Action = deriveAction(report.command, record, head, dirty)
+		encoder.Encode(record)
+	}
+
+	if failures == nil {
+		failures = []failure{}
+	}
+
+	encoder.Encode(summaryRecord{
+		Type:     "summary",
+		Command:  report.command,
+		Success:  len(errs) == 0,
+		ExitCode: exitCodeFor(failures),
+		Stats:    stats,
+		Summary:  summary,
+		Errors:   errs,
+		Failures: failures,
+	})
+
+	exitWithFailures(failures)
+}
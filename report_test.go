@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestReport_RequiredVersion(context *testing.T) {
+	chain := []*library{
+		{Name: "parg", Module: "github.com/hatchify/parg"},
+		{Name: "scribe", Module: "github.com/hatchify/scribe", Requires: []requirement{{Module: "github.com/hatchify/parg", Version: "v0.1.9"}}},
+		{Name: "vroomy", Module: "github.com/vroomy/vroomy", Requires: []requirement{{Module: "github.com/hatchify/parg", Version: "v0.1.29"}}},
+	}
+
+	test := simply.Target(requiredVersion("github.com/hatchify/parg", chain), context, "Required version should be the highest within the chain")
+	result := test.Equals("v0.1.29")
+	test.Validate(result)
+
+	test = simply.Target(requiredVersion("github.com/vroomy/vroomy", chain), context, "Required version should be empty without consumers")
+	result = test.Equals("")
+	test.Validate(result)
+}
+
+func TestReport_DeriveAction(context *testing.T) {
+	cases := []struct {
+		command  string
+		record   libraryRecord
+		head     string
+		dirty    bool
+		expected string
+	}{
+		{"sync", libraryRecord{Error: "parg: failed", head: "a"}, "a", false, "failed"},
+		{"test", libraryRecord{head: "a", dirty: true}, "a", false, "tested"},
+		{"sync", libraryRecord{Tag: "v0.1.1", head: "b"}, "a", false, "tagged"},
+		{"sync", libraryRecord{head: "b"}, "a", false, "committed"},
+		{"pull", libraryRecord{head: "b"}, "a", false, "pulled"},
+		{"replace", libraryRecord{head: "a", Changes: []modChange{{Kind: "replace", Module: "parg", New: "=> ../parg"}}}, "a", false, "replaced"},
+		{"reset", libraryRecord{head: "a", Changes: []modChange{{Kind: "replace", Module: "parg", Old: "=> ../parg"}}}, "a", true, "reset"},
+		{"sync", libraryRecord{head: "a", dirty: true}, "a", false, "modified"},
+		{"replace", libraryRecord{head: "a"}, "a", false, "unchanged"},
+	}
+
+	for _, c := range cases {
+		record := c.record
+		test := simply.Target(deriveAction(c.command, &record, c.head, c.dirty), context, c.command+" should derive "+c.expected)
+		result := test.Equals(c.expected)
+		test.Validate(result)
+	}
+}
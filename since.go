@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// sinceRef is set by the -since flag
+var sinceRef string
+
+// parseSinceDuration parses durations like 90m, 36h, 2d or 1w into the time that long ago
+func parseSinceDuration(value string, now time.Time) (since time.Time, ok bool) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), true
+	}
+
+	if len(value) < 2 {
+		return
+	}
+
+	unit := 24 * time.Hour
+	switch value[len(value)-1] {
+	case 'd':
+	case 'w':
+		unit *= 7
+	default:
+		return
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil {
+		return
+	}
+
+	return now.Add(-time.Duration(n) * unit), true
+}
+
+// hasChangedSince returns true if lib has uncommitted changes,
+// or commits since the given ref, tag, date or duration
+// A ref unknown to the library is treated as changed
+func hasChangedSince(lib *library, since string) bool {
+	if gomu.LibraryFromPath(lib.Dir).File.HasChanges() {
+		return true
+	}
+
+	date := since
+	if t, ok := parseSinceDuration(since, time.Now()); ok {
+		date = t.Format(time.RFC3339)
+	} else if _, err := gitOutput(lib.Dir, "rev-parse", "--verify", "--quiet", since+"^{commit}"); err == nil {
+		count, err := commitsSince(lib.Dir, since)
+		return err != nil || count > 0
+	} else if _, err := time.Parse("2006-01-02", since); err != nil {
+		return true
+	}
+
+	output, err := gitOutput(lib.Dir, "log", "-1", "--format=%H", "--since="+date)
+	return err != nil || len(strings.TrimSpace(output)) > 0
+}
+
+// changedSince limits chain to libraries changed since the given ref, along with their dependents
+func changedSince(chain []*library, since string) (changed []*library) {
+	inChain := make(map[*library]bool, len(chain))
+	for _, lib := range chain {
+		inChain[lib] = true
+	}
+
+	keep := make(map[*library]bool, len(chain))
+	for _, lib := range chain {
+		if hasChangedSince(lib, since) {
+			keep[lib] = true
+		}
+	}
+
+	// Chain is sorted, so a dependency is always decided before its dependents
+	for _, lib := range chain {
+		for _, dep := range lib.Deps {
+			if inChain[dep] && keep[dep] {
+				keep[lib] = true
+			}
+		}
+	}
+
+	for _, lib := range chain {
+		if keep[lib] {
+			changed = append(changed, lib)
+		}
+	}
+
+	return sortChain(changed)
+}
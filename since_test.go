@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/hatchify/simply"
+)
+
+// testGit runs git within dir, failing the test on error
+func testGit(context *testing.T, dir string, args ...string) {
+	args = append([]string{"-c", "user.name=gomu", "-c", "user.email=gomu@example.com", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		context.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+// testRepos commits every library of the test workspace, tagged v0.1.0
+func testRepos(context *testing.T) (root string) {
+	root = testWorkspace(context)
+	for _, name := range []string{"parg", "scribe", "mod-utils", "unrelated"} {
+		dir := filepath.Join(root, name)
+		testGit(context, dir, "init", "-q")
+		testGit(context, dir, "add", "-A")
+		testGit(context, dir, "commit", "-q", "-m", "Initial commit")
+		testGit(context, dir, "tag", "v0.1.0")
+	}
+
+	return
+}
+
+func TestSince_ParseDuration(context *testing.T) {
+	now := time.Date(2020, 5, 15, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"90m": now.Add(-90 * time.Minute),
+		"36h": now.Add(-36 * time.Hour),
+		"2d":  now.AddDate(0, 0, -2),
+		"1w":  now.AddDate(0, 0, -7),
+	}
+
+	for value, expected := range cases {
+		since, ok := parseSinceDuration(value, now)
+		test := simply.Target(ok && since.Equal(expected), context, value+" should be "+expected.String())
+		result := test.Equals(true)
+		test.Validate(result)
+	}
+
+	for _, value := range []string{"v0.5.0", "2020-05-01", "d", "xd", "3y", ""} {
+		_, ok := parseSinceDuration(value, now)
+		test := simply.Target(ok, context, value+" should not be a duration")
+		result := test.Equals(false)
+		test.Validate(result)
+	}
+}
+
+func TestSince_Changed(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	parg := filepath.Join(root, "parg")
+	if err := ioutil.WriteFile(filepath.Join(parg, "parg.go"), []byte("package parg\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	testGit(context, parg, "add", "-A")
+	testGit(context, parg, "commit", "-q", "-m", "Add parg.go")
+
+	chain, _ := loadChain([]string{root}, nil, false)
+
+	var names []string
+	for _, lib := range changedSince(chain, "v0.1.0") {
+		names = append(names, lib.Name)
+	}
+
+	test := simply.Target(names, context, "parg changed, so it and its dependents should be selected")
+	result := test.Equals([]string{"parg", "scribe", "mod-utils"})
+	test.Validate(result)
+
+	// Uncommitted changes count as well
+	if err := ioutil.WriteFile(filepath.Join(root, "unrelated", "unrelated.go"), []byte("package unrelated\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	test = simply.Target(len(changedSince(chain, "v0.1.0")), context, "unrelated has uncommitted changes, so it should be selected")
+	result = test.Equals(4)
+	test.Validate(result)
+}
+
+func TestSince_Scope(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	scribe := filepath.Join(root, "scribe")
+	if err := ioutil.WriteFile(filepath.Join(scribe, "scribe.go"), []byte("package scribe\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	testGit(context, scribe, "add", "-A")
+	testGit(context, scribe, "commit", "-q", "-m", "Add scribe.go")
+
+	sinceRef = "v0.1.0"
+	defer func() { sinceRef = "" }()
+
+	names := scopeNames(context, gomu.Options{TargetDirectories: []string{root}, FilterDependencies: []string{"mod-utils"}})
+
+	test := simply.Target(names, context, "Unchanged parg should not be added back as a dependency of scribe")
+	result := test.Equals([]string{"mod-utils", "scribe"})
+	test.Validate(result)
+}
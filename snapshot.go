@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// manifest pins every library of a chain to an exact commit
+//
+//	created: 2020-05-01T12:30:00Z
+//	libraries:
+//	  - name: parg
+//	    module: github.com/hatchify/parg
+//	    path: /home/gopher/go/src/github.com/hatchify/parg
+//	    remote: git@github.com:hatchify/parg.git
+//	    branch: master
+//	    commit: 4f2c9e1d...
+type manifest struct {
+	Created   time.Time       `json:"created"`
+	Libraries []manifestEntry `json:"libraries"`
+}
+
+// manifestEntry is the state of a single working copy
+type manifestEntry struct {
+	Name   string `json:"name"`
+	Module string `json:"module"`
+	Path   string `json:"path"`
+	Remote string `json:"remote,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Commit string `json:"commit"`
+}
+
+// restoreRecord reports how a working copy was restored
+type restoreRecord struct {
+	Type    string `json:"type"`
+	Library string `json:"library"`
+	Path    string `json:"path"`
+	Commit  string `json:"commit"`
+	// Result is restored, detached, cloned, unchanged or failed
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// snapshotEntry records the current state of lib
+func snapshotEntry(lib *library) (entry manifestEntry, err error) {
+	entry = manifestEntry{Name: lib.Name, Module: lib.Module, Path: lib.Dir}
+	if entry.Commit, err = gitOutput(lib.Dir, "rev-parse", "HEAD"); err != nil {
+		return
+	}
+
+	entry.Remote, _ = gitOutput(lib.Dir, "remote", "get-url", remoteName)
+	if branch, _ := gitOutput(lib.Dir, "rev-parse", "--abbrev-ref", "HEAD"); branch != "HEAD" {
+		entry.Branch = branch
+	}
+
+	return
+}
+
+// writeManifest encodes m as yaml when format is yaml, or json otherwise
+func writeManifest(w io.Writer, m manifest, format string) error {
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+	default:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(m)
+	}
+
+	fmt.Fprintf(w, "created: %s\n", m.Created.UTC().Format(time.RFC3339))
+	fmt.Fprintln(w, "libraries:")
+	for _, entry := range m.Libraries {
+		fmt.Fprintf(w, "  - name: %s\n", entry.Name)
+		fmt.Fprintf(w, "    module: %s\n", entry.Module)
+		fmt.Fprintf(w, "    path: %q\n", entry.Path)
+		if len(entry.Remote) > 0 {
+			fmt.Fprintf(w, "    remote: %q\n", entry.Remote)
+		}
+
+		if len(entry.Branch) > 0 {
+			fmt.Fprintf(w, "    branch: %q\n", entry.Branch)
+		}
+
+		fmt.Fprintf(w, "    commit: %s\n", entry.Commit)
+	}
+
+	return nil
+}
+
+// readManifest decodes a json or yaml manifest
+func readManifest(r io.Reader) (m manifest, err error) {
+	var data []byte
+	if data, err = ioutil.ReadAll(r); err != nil {
+		return
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return m, errors.New("manifest is empty")
+	}
+
+	if data[0] == '{' {
+		err = json.Unmarshal(data, &m)
+		return
+	}
+
+	return parseManifest(bytes.NewReader(data))
+}
+
+// parseManifest reads the yaml written by writeManifest
+func parseManifest(r io.Reader) (m manifest, err error) {
+	var (
+		entry  *manifestEntry
+		lineNo int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(stripConfigComment(scanner.Text()))
+		if len(text) == 0 || text == "---" || text == "libraries:" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "- ") {
+			m.Libraries = append(m.Libraries, manifestEntry{})
+			entry = &m.Libraries[len(m.Libraries)-1]
+			text = strings.TrimSpace(strings.TrimPrefix(text, "- "))
+		}
+
+		key, value, ok := splitConfigKey(text)
+		if !ok {
+			return m, fmt.Errorf("manifest line %d: expected `key: value`", lineNo)
+		}
+
+		value = unquoteConfig(value)
+		if key == "created" {
+			m.Created, _ = time.Parse(time.RFC3339, value)
+			continue
+		}
+
+		if entry == nil {
+			return m, fmt.Errorf("manifest line %d: %s outside of a library", lineNo, key)
+		}
+
+		switch key {
+		case "name":
+			entry.Name = value
+		case "module":
+			entry.Module = value
+		case "path":
+			entry.Path = value
+		case "remote":
+			entry.Remote = value
+		case "branch":
+			entry.Branch = value
+		case "commit":
+			entry.Commit = value
+		}
+	}
+
+	err = scanner.Err()
+	return
+}
+
+// printSnapshot writes a manifest of the chain to stdout
+// -format yaml writes yaml instead of json
+func printSnapshot(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	m := manifest{Created: time.Now().UTC()}
+	for _, lib := range chain {
+		entry, err := snapshotEntry(lib)
+		if err != nil {
+			return fmt.Errorf("%s: %v", lib.Name, err)
+		}
+
+		if dirty, _ := gitOutput(lib.Dir, "status", "--porcelain"); len(dirty) > 0 {
+			warn(lib.Name + " has uncommitted changes which are not part of the snapshot")
+		}
+
+		m.Libraries = append(m.Libraries, entry)
+	}
+
+	if outputJSON {
+		return writeManifest(jsonOut, m, "json")
+	}
+
+	return writeManifest(os.Stdout, m, outputFormat)
+}
+
+// restoreEntry checks out the commit of entry, cloning the working copy if it is missing
+func restoreEntry(entry manifestEntry) (record restoreRecord) {
+	record = restoreRecord{Type: "restore", Library: entry.Name, Path: entry.Path, Commit: entry.Commit}
+	fail := func(err error) restoreRecord {
+		record.Result = "failed"
+		record.Error = err.Error()
+		return record
+	}
+
+	if _, err := os.Stat(entry.Path); err != nil {
+		record.Path = cloneDir(entry.Module)
+	}
+
+	lib := &library{Name: entry.Name, Module: entry.Module, Dir: record.Path}
+	if _, err := os.Stat(lib.Dir); err != nil {
+		if len(entry.Remote) == 0 {
+			return fail(fmt.Errorf("%s: missing and no remote to clone from", entry.Name))
+		}
+
+		record.Result = "cloned"
+		if dryRun {
+			return
+		}
+
+		if err := cloneRepo(lib, entry.Remote); err != nil {
+			return fail(err)
+		}
+	} else if dirty, _ := gitOutput(lib.Dir, "status", "--porcelain"); len(dirty) > 0 {
+		return fail(fmt.Errorf("%s: has uncommitted changes, stash them first", entry.Name))
+	}
+
+	if head, _ := gitOutput(lib.Dir, "rev-parse", "HEAD"); head == entry.Commit && len(record.Result) == 0 {
+		record.Result = "unchanged"
+		return
+	}
+
+	if _, err := gitOutput(lib.Dir, "cat-file", "-e", entry.Commit+"^{commit}"); err != nil && !dryRun {
+		if err := runCommand(lib, ioutil.Discard, "git", "fetch", "--tags", remoteName); err != nil {
+			return fail(err)
+		}
+	}
+
+	// Only check out the branch when it still points at the commit, otherwise detach
+	result := "detached"
+	if len(entry.Branch) > 0 {
+		local, _ := gitOutput(lib.Dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+entry.Branch)
+		remote, _ := gitOutput(lib.Dir, "rev-parse", "--verify", "--quiet", "refs/remotes/"+remoteName+"/"+entry.Branch)
+		if local == entry.Commit || (len(local) == 0 && remote == entry.Commit) {
+			result = "restored"
+		}
+	}
+
+	if len(record.Result) == 0 {
+		record.Result = result
+	}
+
+	if dryRun {
+		return
+	}
+
+	var err error
+	if result == "restored" {
+		_, err = switchBranch(lib, ioutil.Discard, entry.Branch)
+	} else {
+		err = runCommand(lib, ioutil.Discard, "git", "checkout", "--detach", entry.Commit)
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	return
+}
+
+// runRestore checks every working copy out to the commits of the manifest named by the first argument
+// Remaining arguments limit which libraries of the manifest are restored
+func runRestore(options gomu.Options) (err error) {
+	if len(options.FilterDependencies) == 0 {
+		return errors.New("restore requires a manifest, usage: `gomu restore <manifest> <libraries>`")
+	}
+
+	filename, filters := options.FilterDependencies[0], options.FilterDependencies[1:]
+
+	var f *os.File
+	if f, err = os.Open(filename); err != nil {
+		return
+	}
+	defer f.Close()
+
+	var m manifest
+	if m, err = readManifest(f); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	var (
+		records  []restoreRecord
+		failures []failure
+	)
+
+	for _, entry := range m.Libraries {
+		if !manifestSelected(entry, filters) {
+			continue
+		}
+
+		record := restoreEntry(entry)
+		if len(record.Error) > 0 {
+			failures = append(failures, failure{Library: record.Library, Category: categoryGit, Error: record.Error})
+		}
+
+		records = append(records, record)
+	}
+
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, record := range records {
+			encoder.Encode(record)
+		}
+
+		exitWithFailures(failures)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%.8s\t%s\n", record.Library, record.Result, record.Commit, record.Path)
+	}
+
+	w.Flush()
+	if len(failures) > 0 {
+		printFailures(failures)
+	}
+
+	exitWithFailures(failures)
+	return
+}
+
+// manifestSelected returns true if entry matches any of filters, or there are none
+func manifestSelected(entry manifestEntry, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+
+	lib := &library{Name: entry.Name, Module: entry.Module, Dir: entry.Path}
+	for _, filter := range filters {
+		if lib.Matches(filter) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/hatchify/simply"
+)
+
+func TestManifest_RoundTrip(context *testing.T) {
+	m := manifest{
+		Created: time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC),
+		Libraries: []manifestEntry{
+			{Name: "parg", Module: "github.com/hatchify/parg", Path: "/go/src/github.com/hatchify/parg", Remote: "git@github.com:hatchify/parg.git", Branch: "master", Commit: "4f2c9e1d"},
+			{Name: "scribe", Module: "github.com/hatchify/scribe", Path: "/go/src/github.com/hatchify/scribe", Commit: "9a7b3c2e"},
+		},
+	}
+
+	for _, format := range []string{"yaml", "json"} {
+		var buf bytes.Buffer
+		writeManifest(&buf, m, format)
+
+		parsed, err := readManifest(&buf)
+
+		test := simply.Target(err, context, format+" manifest should parse")
+		result := test.Assert().Equals(nil)
+		test.Validate(result)
+
+		test = simply.Target(parsed.Libraries, context, format+" manifest should keep every library")
+		result = test.Equals(m.Libraries)
+		test.Validate(result)
+
+		test = simply.Target(parsed.Created.Equal(m.Created), context, format+" manifest should keep its timestamp")
+		result = test.Equals(true)
+		test.Validate(result)
+	}
+}
+
+func TestManifest_RoundTripEscapes(context *testing.T) {
+	m := manifest{
+		Created: time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC),
+		Libraries: []manifestEntry{
+			{Name: "parg", Module: "github.com/hatchify/parg", Path: `C:\src\"quoted" #parg`, Branch: `feature\x`, Commit: "4f2c9e1d"},
+		},
+	}
+
+	var buf bytes.Buffer
+	writeManifest(&buf, m, "yaml")
+
+	parsed, err := readManifest(&buf)
+
+	test := simply.Target(err, context, "yaml manifest should parse")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(parsed.Libraries, context, "Backslashes, quotes and # in paths should survive a round trip")
+	result = test.Equals(m.Libraries)
+	test.Validate(result)
+}
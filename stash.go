@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// stashPrefix marks stash entries created by gomu
+const stashPrefix = "gomu/"
+
+// stashRecord reports what happened to the working copy of a library
+type stashRecord struct {
+	Type    string `json:"type"`
+	Library string `json:"library"`
+	Label   string `json:"label,omitempty"`
+	// Result is stashed, restored, conflict, clean, missing or failed
+	Result    string   `json:"result"`
+	Conflicts []string `json:"conflicts,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// stashLabel names a stash entry, shared by every library stashed together
+func stashLabel(name string, now time.Time) string {
+	if len(name) == 0 {
+		name = "stash"
+	}
+
+	return stashPrefix + name + " " + now.UTC().Format(time.RFC3339)
+}
+
+// gomuStashes returns the gomu stash entries of lib, newest first, mapping their label to their ref
+func gomuStashes(lib *library) (refs map[string]string, labels []string) {
+	refs = make(map[string]string)
+	for _, line := range gitLines(lib.Dir, "stash", "list", "--format=%gd%x1f%gs") {
+		fields := strings.SplitN(line, "\x1f", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		// Subjects look like `On branch: gomu/name timestamp`
+		i := strings.Index(fields[1], ": "+stashPrefix)
+		if i == -1 {
+			continue
+		}
+
+		label := fields[1][i+2:]
+		if _, ok := refs[label]; !ok {
+			refs[label] = fields[0]
+			labels = append(labels, label)
+		}
+	}
+
+	return
+}
+
+// stashedAt returns the timestamp of a stash label
+func stashedAt(label string) (t time.Time) {
+	if i := strings.LastIndex(label, " "); i != -1 {
+		t, _ = time.Parse(time.RFC3339, label[i+1:])
+	}
+
+	return
+}
+
+// latestStash returns the newest gomu stash label across the chain, optionally limited to a name
+func latestStash(chain []*library, name string) (latest string) {
+	for _, lib := range chain {
+		_, labels := gomuStashes(lib)
+		for _, label := range labels {
+			if len(name) > 0 && !strings.HasPrefix(label, stashPrefix+name+" ") {
+				continue
+			}
+
+			if len(latest) == 0 || stashedAt(label).After(stashedAt(latest)) {
+				latest = label
+			}
+		}
+	}
+
+	return
+}
+
+// stashLibrary stashes tracked and untracked changes of lib under label
+func stashLibrary(lib *library, label string) (record stashRecord) {
+	record = stashRecord{Type: "stash", Library: lib.Name, Label: label}
+	if changes, err := gitOutput(lib.Dir, "status", "--porcelain"); err != nil {
+		record.Result = "failed"
+		record.Error = err.Error()
+		return
+	} else if len(changes) == 0 {
+		record.Result = "clean"
+		record.Label = ""
+		return
+	}
+
+	record.Result = "stashed"
+	if dryRun {
+		return
+	}
+
+	if err := runCommand(lib, ioutil.Discard, "git", "stash", "push", "--include-untracked", "-m", label); err != nil {
+		record.Result = "failed"
+		record.Error = err.Error()
+	}
+
+	return
+}
+
+// unstashLibrary pops the stash entry of lib matching label
+// On conflict the entry is kept so nothing is lost
+func unstashLibrary(lib *library, label string) (record stashRecord) {
+	record = stashRecord{Type: "unstash", Library: lib.Name, Label: label}
+	refs, _ := gomuStashes(lib)
+	ref, ok := refs[label]
+	if !ok {
+		record.Result = "missing"
+		record.Label = ""
+		return
+	}
+
+	record.Result = "restored"
+	if dryRun {
+		return
+	}
+
+	if err := runCommand(lib, ioutil.Discard, "git", "stash", "pop", "--index", ref); err != nil {
+		record.Result = "failed"
+		record.Error = err.Error()
+		if record.Conflicts = gitLines(lib.Dir, "diff", "--name-only", "--diff-filter=U"); len(record.Conflicts) > 0 {
+			record.Result = "conflict"
+		}
+	}
+
+	return
+}
+
+// runStash parks uncommitted work of every library in the chain under one gomu stash entry
+// -message names the entry
+func runStash(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	label := stashLabel(options.CommitMessage, time.Now())
+	records := make([]stashRecord, 0, len(chain))
+	for _, lib := range chain {
+		records = append(records, stashLibrary(lib, label))
+	}
+
+	printStashRecords(records)
+	return
+}
+
+// runUnstash restores the newest gomu stash entry, or the newest named -message, across the chain
+func runUnstash(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	label := latestStash(chain, options.CommitMessage)
+	if len(label) == 0 {
+		return errors.New("no gomu stash found, create one with `gomu stash -m <name>`")
+	}
+
+	records := make([]stashRecord, 0, len(chain))
+	for _, lib := range chain {
+		records = append(records, unstashLibrary(lib, label))
+	}
+
+	printStashRecords(records)
+	return
+}
+
+// printStashRecords prints a row per library and exits with the failures, if any
+func printStashRecords(records []stashRecord) {
+	var failures []failure
+	for _, record := range records {
+		if len(record.Error) > 0 {
+			failures = append(failures, failure{Library: record.Library, Category: categoryGit, Error: record.Error})
+		}
+	}
+
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, record := range records {
+			encoder.Encode(record)
+		}
+
+		exitWithFailures(failures)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", record.Library, record.Result, record.Label, strings.Join(record.Conflicts, " "))
+	}
+
+	w.Flush()
+	if len(failures) > 0 {
+		printFailures(failures)
+	}
+
+	exitWithFailures(failures)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hatchify/simply"
+)
+
+func TestStashLabel(context *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC)
+	label := stashLabel("before-sync", now)
+
+	test := simply.Target(label, context, "Stash label should be prefixed, named and timestamped")
+	result := test.Equals("gomu/before-sync 2020-05-01T12:30:00Z")
+	test.Validate(result)
+
+	test = simply.Target(stashedAt(label).Equal(now), context, "Stash timestamp should parse back")
+	result = test.Equals(true)
+	test.Validate(result)
+}
+
+// testIdentity lets git stash commit without a configured user
+func testIdentity() (restore func()) {
+	vars := map[string]string{"GIT_AUTHOR_NAME": "gomu", "GIT_AUTHOR_EMAIL": "gomu@example.com", "GIT_COMMITTER_NAME": "gomu", "GIT_COMMITTER_EMAIL": "gomu@example.com"}
+	previous := make(map[string]string, len(vars))
+	for key, value := range vars {
+		if old, ok := os.LookupEnv(key); ok {
+			previous[key] = old
+		}
+
+		os.Setenv(key, value)
+	}
+
+	return func() {
+		for key := range vars {
+			if old, ok := previous[key]; ok {
+				os.Setenv(key, old)
+			} else {
+				os.Unsetenv(key)
+			}
+		}
+	}
+}
+
+func TestStash_RoundTrip(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+	defer testIdentity()()
+
+	dir := filepath.Join(root, "parg")
+	modFile := "module github.com/hatchify/parg\n\ngo 1.15\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(modFile), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "new.go"), []byte("package parg\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	lib := &library{Name: "parg", Dir: dir}
+	label := stashLabel("round-trip", time.Now())
+	record := stashLibrary(lib, label)
+
+	test := simply.Target(record.Result, context, "Changes should be stashed")
+	result := test.Equals("stashed")
+	test.Validate(result)
+
+	status, _ := gitOutput(dir, "status", "--porcelain")
+
+	test = simply.Target(status, context, "Tracked and untracked changes should be stashed")
+	result = test.Equals("")
+	test.Validate(result)
+
+	record = unstashLibrary(lib, label)
+
+	test = simply.Target(record.Result, context, "Changes should be restored")
+	result = test.Equals("restored")
+	test.Validate(result)
+
+	restored, _ := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+
+	test = simply.Target(string(restored), context, "Tracked changes should be restored")
+	result = test.Equals(modFile)
+	test.Validate(result)
+
+	_, err := os.Stat(filepath.Join(dir, "new.go"))
+
+	test = simply.Target(err, context, "Untracked files should be restored")
+	result = test.Assert().Equals(nil)
+	test.Validate(result)
+
+	refs, _ := gomuStashes(lib)
+
+	test = simply.Target(len(refs), context, "The stash entry should be dropped once restored")
+	result = test.Equals(0)
+	test.Validate(result)
+}
+
+func TestStash_Conflict(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+	defer testIdentity()()
+
+	dir := filepath.Join(root, "parg")
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module github.com/hatchify/parg\n\ngo 1.15\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	lib := &library{Name: "parg", Dir: dir}
+	label := stashLabel("conflict", time.Now())
+	stashLibrary(lib, label)
+
+	// go.mod changes the same line upstream of the stash
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module github.com/hatchify/parg\n\ngo 1.16\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	testGit(context, dir, "commit", "-q", "-am", "Bump go")
+
+	record := unstashLibrary(lib, label)
+
+	test := simply.Target(record.Result, context, "Restoring onto a conflicting change should conflict")
+	result := test.Equals("conflict")
+	test.Validate(result)
+
+	test = simply.Target(record.Conflicts, context, "go.mod should be listed as conflicting")
+	result = test.Equals([]string{"go.mod"})
+	test.Validate(result)
+
+	refs, _ := gomuStashes(lib)
+
+	test = simply.Target(len(refs), context, "The stash entry should be kept on conflict")
+	result = test.Equals(1)
+	test.Validate(result)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// libraryStatus is a row of the status table
+type libraryStatus struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Branch   string `json:"branch"`
+	Dirty    bool   `json:"dirty"`
+	Upstream bool   `json:"upstream"`
+	Ahead    int    `json:"ahead"`
+	Behind   int    `json:"behind"`
+	Tag      string `json:"tag"`
+	// SinceTag is the number of commits since Tag, -1 without a tag
+	SinceTag int `json:"sinceTag"`
+	Replaces int `json:"replaces"`
+}
+
+func statusOf(lib *library) (status libraryStatus) {
+	tool := gomu.LibraryFromPath(lib.Dir)
+
+	status = libraryStatus{Type: "status", Name: lib.Name, Path: lib.Dir, SinceTag: -1, Replaces: len(lib.Replaces)}
+	status.Branch, _ = tool.File.CurrentBranch()
+	status.Branch = strings.TrimSpace(status.Branch)
+	status.Dirty = tool.File.HasChanges()
+
+	if output, err := gitOutput(lib.Dir, "rev-list", "--left-right", "--count", "HEAD...@{u}"); err == nil {
+		if counts := strings.Fields(output); len(counts) == 2 {
+			status.Upstream = true
+			status.Ahead, _ = strconv.Atoi(counts[0])
+			status.Behind, _ = strconv.Atoi(counts[1])
+		}
+	}
+
+	if status.Tag = tool.GetLatestTag(); len(status.Tag) > 0 {
+		if count, err := commitsSince(lib.Dir, status.Tag); err == nil {
+			status.SinceTag = count
+		}
+	}
+
+	return
+}
+
+// printStatus prints a row per library with its branch, changes, upstream and tag state
+func printStatus(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, lib := range chain {
+			encoder.Encode(statusOf(lib))
+		}
+
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LIBRARY\tBRANCH\tDIRTY\tAHEAD\tBEHIND\tTAG\tSINCE TAG\tREPLACES")
+	for _, lib := range chain {
+		status := statusOf(lib)
+
+		dirty := ""
+		if status.Dirty {
+			dirty = "*"
+		}
+
+		ahead, behind := "-", "-"
+		if status.Upstream {
+			ahead, behind = strconv.Itoa(status.Ahead), strconv.Itoa(status.Behind)
+		}
+
+		tag, sinceTag := "-", "-"
+		if len(status.Tag) > 0 {
+			tag = status.Tag
+		}
+
+		if status.SinceTag >= 0 {
+			sinceTag = strconv.Itoa(status.SinceTag)
+		}
+
+		replaces := ""
+		if status.Replaces > 0 {
+			replaces = strconv.Itoa(status.Replaces)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", status.Name, status.Branch, dirty, ahead, behind, tag, sinceTag, replaces)
+	}
+
+	return w.Flush()
+}
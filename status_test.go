@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestStatus_Library(context *testing.T) {
+	root := testRepos(context)
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "mod-utils")
+	if err := ioutil.WriteFile(filepath.Join(dir, "mu.go"), []byte("package mu\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	testGit(context, dir, "add", "-A")
+	testGit(context, dir, "commit", "-q", "-m", "Add mu.go")
+	if err := ioutil.WriteFile(filepath.Join(dir, "wip.go"), []byte("package mu\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	lib, _ := libraryFromDir(dir)
+	status := statusOf(lib)
+
+	test := simply.Target(status.Tag, context, "Tag should be v0.1.0")
+	result := test.Equals("v0.1.0")
+	test.Validate(result)
+
+	test = simply.Target(status.SinceTag, context, "There should be 1 commit since the tag")
+	result = test.Equals(1)
+	test.Validate(result)
+
+	test = simply.Target(status.Dirty, context, "Untracked files should make the library dirty")
+	result = test.Equals(true)
+	test.Validate(result)
+
+	test = simply.Target(status.Upstream, context, "There should be no upstream")
+	result = test.Equals(false)
+	test.Validate(result)
+
+	test = simply.Target(status.Replaces, context, "mod-utils replaces parg")
+	result = test.Equals(1)
+	test.Validate(result)
+}
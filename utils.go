@@ -2,7 +2,9 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -12,28 +14,55 @@ import (
 
 var logLevel = "NORMAL"
 
-func readInput() {
+// readInput returns the newline separated names read from r
+func readInput(r io.Reader) (files []string) {
 	var (
 		err  error
 		text string
 	)
 
-	files := make([]string, 0)
-	reader := bufio.NewReader(os.Stdin)
+	files = make([]string, 0)
+	reader := bufio.NewReader(r)
 
-	// Get files from stdin (piped from another program's output)
-	for err == nil {
+	// Get files from r, usually another program's output piped to stdin
+	for {
+		text, err = reader.ReadString('\n')
 		if text = strings.TrimSpace(text); len(text) > 0 {
 			files = append(files, text)
 		}
 
-		text, err = reader.ReadString('\n')
+		if err != nil {
+			return
+		}
 	}
+}
 
-	// Print files
-	for i := range files {
-		fmt.Println(files[i])
+// filtersFromInput replaces a `-` argument with the names read from r
+// stdin is only read when asked for, so gomu never blocks on (or drains) input meant for something else
+func filtersFromInput(args []string, r io.Reader) (filters []string, err error) {
+	for i, arg := range args {
+		if arg != "-" {
+			continue
+		}
+
+		names := readInput(r)
+		if len(names) == 0 {
+			// An empty filter would select every library
+			return nil, errors.New("no library names read from stdin")
+		}
+
+		filters = append(filters, args[:i]...)
+		filters = append(filters, names...)
+		for _, rest := range args[i+1:] {
+			if rest != "-" {
+				filters = append(filters, rest)
+			}
+		}
+
+		return
 	}
+
+	return args, nil
 }
 
 func showHelp(cmd *flag.Command) {
@@ -44,7 +73,13 @@ func showHelp(cmd *flag.Command) {
 	}
 }
 
+// warn prints a diagnostic to stderr, so it never mixes with output redirected from stdout
+func warn(message string) {
+	fmt.Fprintln(os.Stderr, ":: gomu :: "+message)
+}
+
 func exitWithError(message string) {
 	com.Errorln(message)
-	os.Exit(1)
+	removeScope()
+	os.Exit(exitFailure)
 }
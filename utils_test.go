@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestInput_Filters(context *testing.T) {
+	filters, err := filtersFromInput([]string{"parg", "-", "scribe"}, strings.NewReader("svc-a\n\nsvc-b"))
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(filters, context, "Piped names should replace -, including a last line without a newline")
+	result = test.Equals([]string{"parg", "svc-a", "svc-b", "scribe"})
+	test.Validate(result)
+
+	filters, _ = filtersFromInput([]string{"parg"}, strings.NewReader("svc-a\n"))
+
+	test = simply.Target(filters, context, "stdin should not be read without -")
+	result = test.Equals([]string{"parg"})
+	test.Validate(result)
+
+	_, err = filtersFromInput([]string{"-"}, strings.NewReader("\n"))
+
+	test = simply.Target(err != nil, context, "Reading no names should fail rather than select every library")
+	result = test.Equals(true)
+	test.Validate(result)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// whyHop is a single requirement along a path to the module in question
+type whyHop struct {
+	Library string `json:"library"`
+	Module  string `json:"module"`
+	Version string `json:"version,omitempty"`
+	// File is the go.mod (with line number) or go.sum that pulls the module in
+	File string `json:"file"`
+}
+
+// String formats the hop as `file  require module version`
+func (hop whyHop) String() string {
+	if len(hop.Version) == 0 {
+		return fmt.Sprintf("%s  references %s", hop.File, hop.Module)
+	}
+
+	return fmt.Sprintf("%s  require %s %s", hop.File, hop.Module, hop.Version)
+}
+
+// hopTo returns how lib pulls module in
+func hopTo(lib *library, module string, direct bool) (hop whyHop, ok bool) {
+	hop = whyHop{Library: lib.Name, Module: module}
+	if req, found := lib.Requirement(module); found {
+		hop.Version = req.Version
+		hop.File = fmt.Sprintf("%s:%d", filepath.Join(lib.Dir, "go.mod"), req.Line)
+		return hop, true
+	}
+
+	if direct {
+		return
+	}
+
+	for _, sum := range lib.Sums {
+		if sum == module {
+			hop.File = filepath.Join(lib.Dir, "go.sum")
+			return hop, true
+		}
+	}
+
+	return
+}
+
+// maxWhyPaths bounds how many paths are returned for a single library
+const maxWhyPaths = 100
+
+// whyPaths returns up to maxWhyPaths paths from root to module through local libraries
+// Only go.mod requirements are followed between libraries, a go.sum reference is only accepted for the last hop
+func whyPaths(root *library, module string, direct bool) (paths [][]whyHop) {
+	reaches := whyReaches(root, module, direct)
+	onPath := make(map[*library]bool)
+
+	var walk func(lib *library, hops []whyHop)
+	walk = func(lib *library, hops []whyHop) {
+		if hop, ok := hopTo(lib, module, direct); ok {
+			path := make([]whyHop, len(hops), len(hops)+1)
+			copy(path, hops)
+			paths = append(paths, append(path, hop))
+		}
+
+		onPath[lib] = true
+		for _, dep := range lib.Deps {
+			if len(paths) >= maxWhyPaths {
+				break
+			}
+
+			if onPath[dep] || !reaches[dep] || dep.Module == module {
+				continue
+			}
+
+			if hop, ok := hopTo(lib, dep.Module, true); ok {
+				walk(dep, append(hops, hop))
+			}
+		}
+
+		delete(onPath, lib)
+	}
+
+	walk(root, nil)
+	return
+}
+
+// whyReaches returns the libraries below root which pull module in, so whyPaths never walks a dead end
+func whyReaches(root *library, module string, direct bool) (reaches map[*library]bool) {
+	reaches = make(map[*library]bool)
+
+	var libs []*library
+	seen := map[*library]bool{root: true}
+	for queue := []*library{root}; len(queue) > 0; queue = queue[1:] {
+		lib := queue[0]
+		libs = append(libs, lib)
+		if _, ok := hopTo(lib, module, direct); ok {
+			reaches[lib] = true
+		}
+
+		for _, dep := range lib.Deps {
+			if _, ok := hopTo(lib, dep.Module, true); ok && !seen[dep] {
+				seen[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, lib := range libs {
+			if reaches[lib] {
+				continue
+			}
+
+			for _, dep := range lib.Deps {
+				if _, ok := hopTo(lib, dep.Module, true); ok && reaches[dep] {
+					reaches[lib] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// printWhy prints every path from the selected libraries to the module named by the first argument
+func printWhy(options gomu.Options) (err error) {
+	if len(options.FilterDependencies) == 0 {
+		return errors.New("why requires a module, usage: `gomu why <module> <libraries>`")
+	}
+
+	target, filters := options.FilterDependencies[0], options.FilterDependencies[1:]
+
+	var libs []*library
+	if libs, err = discoverLibraries(options.TargetDirectories); err != nil {
+		return
+	}
+
+	// Link every library so paths can pass through any local working copy
+	dependencyChain(libs, nil, options.DirectImport)
+
+	module := target
+	for _, lib := range libs {
+		if lib.Matches(target) {
+			module = lib.Module
+		}
+	}
+
+	type whyRecord struct {
+		Type  string   `json:"type"`
+		Root  string   `json:"root"`
+		Path  []whyHop `json:"path"`
+		Chain []string `json:"chain"`
+	}
+
+	var records []whyRecord
+	for _, lib := range libs {
+		if lib.Module == module {
+			continue
+		}
+
+		if len(filters) > 0 {
+			matched := false
+			for _, filter := range filters {
+				matched = matched || lib.Matches(filter)
+			}
+
+			if !matched {
+				continue
+			}
+		}
+
+		paths := whyPaths(lib, module, options.DirectImport)
+		if len(paths) >= maxWhyPaths {
+			warn(fmt.Sprintf("Only the first %d paths from %s are listed", maxWhyPaths, lib.Name))
+		}
+
+		for _, path := range paths {
+			record := whyRecord{Type: "why", Root: lib.Name, Path: path, Chain: []string{lib.Name}}
+			for _, hop := range path[1:] {
+				record.Chain = append(record.Chain, hop.Library)
+			}
+
+			record.Chain = append(record.Chain, module)
+
+			records = append(records, record)
+		}
+	}
+
+	if outputJSON {
+		encoder := json.NewEncoder(jsonOut)
+		for _, record := range records {
+			encoder.Encode(record)
+		}
+
+		return
+	}
+
+	fmt.Printf("# why %s\n", module)
+	if len(records) == 0 {
+		fmt.Println("Not required by any selected library.")
+		return
+	}
+
+	for _, record := range records {
+		fmt.Println()
+		fmt.Println(strings.Join(record.Chain, " -> "))
+		for _, hop := range record.Path {
+			fmt.Println("  " + hop.String())
+		}
+	}
+
+	return
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hatchify/simply"
+)
+
+func TestWhy_Paths(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	libs, _ := discoverLibraries([]string{root})
+	dependencyChain(libs, nil, false)
+
+	var modUtils *library
+	for _, lib := range libs {
+		if lib.Name == "mod-utils" {
+			modUtils = lib
+		}
+	}
+
+	paths := whyPaths(modUtils, "github.com/hatchify/parg", false)
+
+	test := simply.Target(len(paths), context, "There should be 1 path to parg")
+	result := test.Equals(1)
+	test.Validate(result)
+
+	test = simply.Target(len(paths[0]), context, "Path should pass through scribe")
+	result = test.Equals(2)
+	test.Validate(result)
+
+	test = simply.Target(paths[0][1].Library, context, "Last hop should be from scribe")
+	result = test.Equals("scribe")
+	test.Validate(result)
+}
+
+func TestWhy_LongChain(context *testing.T) {
+	// Every library requires the next one and references all later ones, as go.sum does
+	chain := make([]*library, 20)
+	for i := range chain {
+		chain[i] = &library{Name: fmt.Sprintf("lib%d", i), Module: fmt.Sprintf("github.com/hatchify/lib%d", i)}
+	}
+
+	target := "github.com/hatchify/target"
+	for i, lib := range chain {
+		lib.Sums = []string{target}
+		if i == len(chain)-1 {
+			lib.Requires = []requirement{{Module: target, Version: "v0.1.0"}}
+			continue
+		}
+
+		lib.Requires = []requirement{{Module: chain[i+1].Module, Version: "v0.1.0"}}
+		for _, dep := range chain[i+1:] {
+			lib.Deps = append(lib.Deps, dep)
+			lib.Sums = append(lib.Sums, dep.Module)
+		}
+	}
+
+	paths := whyPaths(chain[0], target, false)
+
+	test := simply.Target(len(paths), context, "Each library should end one path through go.mod requirements")
+	result := test.Equals(len(chain))
+	test.Validate(result)
+
+	paths = whyPaths(chain[0], target, true)
+
+	test = simply.Target(len(paths), context, "Only the require chain should remain with -direct")
+	result = test.Equals(1)
+	test.Validate(result)
+
+	test = simply.Target(len(paths[0]), context, "The require chain should pass through every library")
+	result = test.Equals(len(chain))
+	test.Validate(result)
+}
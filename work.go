@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gomu "github.com/gomuserver/mod-utils"
+)
+
+// minWorkVersion is the first go release supporting go.work files
+const minWorkVersion = "1.18"
+
+// workRecord reports the generated go.work file
+type workRecord struct {
+	Type string   `json:"type"`
+	Path string   `json:"path"`
+	Go   string   `json:"go"`
+	Use  []string `json:"use"`
+}
+
+// forceWork is set by the -force flag
+var forceWork bool
+
+// workPath returns where the go.work file is written: -source when set, or the working directory
+// A -source directory receives a go.work file within it
+func workPath(source string) (filename string, err error) {
+	if len(source) == 0 {
+		if source, err = os.Getwd(); err != nil {
+			return
+		}
+	}
+
+	if info, statErr := os.Stat(source); statErr == nil && info.IsDir() {
+		source = filepath.Join(source, "go.work")
+	}
+
+	return absPath(source), nil
+}
+
+// capturedModules returns the modules beneath dir which go commands would run in using the go.work file,
+// though it does not use them
+func capturedModules(dir string, chain []*library) (captured []string) {
+	inChain := make(map[string]bool, len(chain))
+	for _, lib := range chain {
+		inChain[absPath(lib.Dir)] = true
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !inChain[dir] {
+		captured = append(captured, dir)
+	}
+
+	libs, _ := discoverLibraries([]string{dir})
+	for _, lib := range libs {
+		if !inChain[absPath(lib.Dir)] {
+			captured = append(captured, lib.Name)
+		}
+	}
+
+	return
+}
+
+func absPath(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+
+	return dir
+}
+
+// workFile renders the go.work file filename using every library of the chain, relative to its directory
+func workFile(filename string, chain []*library) (record workRecord, data []byte) {
+	dir := filepath.Dir(filename)
+	record = workRecord{Type: "work", Path: filename, Go: minWorkVersion}
+	for _, lib := range chain {
+		if len(lib.GoVersion) > 0 && compareVersions(lib.GoVersion, record.Go) > 0 {
+			record.Go = lib.GoVersion
+		}
+
+		use := absPath(lib.Dir)
+		if rel, err := filepath.Rel(dir, use); err == nil {
+			use = filepath.ToSlash(rel)
+			if !strings.HasPrefix(use, "../") {
+				use = "./" + use
+			}
+		}
+
+		record.Use = append(record.Use, use)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Generated by `gomu work`, remove it to use the versions required by go.mod again\n\n")
+	fmt.Fprintf(&buf, "go %s\n\nuse (\n", record.Go)
+	for _, use := range record.Use {
+		fmt.Fprintf(&buf, "\t%s\n", use)
+	}
+
+	fmt.Fprintln(&buf, ")")
+	return record, buf.Bytes()
+}
+
+// writeWork generates a go.work file pointing at the local working copy of every library in the chain
+// Unlike replace, no go.mod is edited, so there is nothing to reset before committing
+// An existing go.work file is only replaced with -force
+func writeWork(options gomu.Options) (err error) {
+	var chain []*library
+	if chain, err = loadChain(options.TargetDirectories, options.FilterDependencies, options.DirectImport); err != nil {
+		return
+	}
+
+	if len(chain) == 0 {
+		return fmt.Errorf("no libraries found in %s", strings.Join(options.TargetDirectories, ", "))
+	}
+
+	var filename string
+	if filename, err = workPath(options.SourcePath); err != nil {
+		return
+	}
+
+	if _, statErr := os.Stat(filename); statErr == nil && !forceWork {
+		return fmt.Errorf("%s already exists, pass -force to replace it", filename)
+	}
+
+	dir := filepath.Dir(filename)
+	record, data := workFile(filename, chain)
+	if captured := capturedModules(dir, chain); len(captured) > 0 {
+		warn(fmt.Sprintf("%s does not use %s beneath it, go commands there will fail until GOWORK=off", record.Path, strings.Join(captured, ", ")))
+	}
+
+	if repo, err := gitOutput(dir, "rev-parse", "--show-toplevel"); err == nil {
+		warn(record.Path + " is inside the working copy of " + repo + ", do not commit it")
+	}
+
+	if dryRun {
+		fmt.Printf("Would write %s:\n\n%s", record.Path, data)
+	} else if err = ioutil.WriteFile(record.Path, data, 0644); err != nil {
+		return
+	}
+
+	if outputJSON {
+		return json.NewEncoder(jsonOut).Encode(record)
+	}
+
+	if dryRun {
+		return
+	}
+
+	fmt.Printf("Wrote %s using %d libraries\n", record.Path, len(record.Use))
+	fmt.Printf("Go commands beneath %s now build against the local copies.\n", dir)
+	fmt.Printf("Elsewhere, run `export GOWORK=%s`.\n", record.Path)
+	if gowork := os.Getenv("GOWORK"); len(gowork) > 0 && !samePath(gowork, record.Path) {
+		warn("GOWORK is set to " + gowork + ", which takes precedence")
+	}
+
+	return
+}
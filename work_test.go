@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomu "github.com/gomuserver/mod-utils"
+	"github.com/hatchify/simply"
+)
+
+func TestWork_File(context *testing.T) {
+	chain := []*library{
+		{Name: "parg", Dir: "/src/hatchify/parg", GoVersion: "1.14"},
+		{Name: "vroomy", Dir: "/src/vroomy/vroomy", GoVersion: "1.21"},
+	}
+
+	record, _ := workFile("/src/hatchify/go.work", chain)
+
+	test := simply.Target(record.Use, context, "go.work should use every library relative to itself")
+	result := test.Equals([]string{"./parg", "../vroomy/vroomy"})
+	test.Validate(result)
+
+	test = simply.Target(record.Go, context, "go.work should use the highest go version")
+	result = test.Equals("1.21")
+	test.Validate(result)
+}
+
+func TestWork_Write(context *testing.T) {
+	root := testWorkspace(context)
+	defer os.RemoveAll(root)
+
+	options := gomu.Options{TargetDirectories: []string{root}, FilterDependencies: []string{"scribe"}, SourcePath: root}
+	err := writeWork(options)
+
+	test := simply.Target(err, context, "Error should not exist")
+	result := test.Assert().Equals(nil)
+	test.Validate(result)
+
+	filename := filepath.Join(root, "go.work")
+	_, err = os.Stat(filename)
+
+	test = simply.Target(err, context, "go.work should be written to the -source directory")
+	result = test.Assert().Equals(nil)
+	test.Validate(result)
+
+	test = simply.Target(capturedModules(root, []*library{{Dir: filepath.Join(root, "parg")}, {Dir: filepath.Join(root, "scribe")}}), context, "Modules beneath go.work but not in it should be reported")
+	result = test.Equals([]string{"mod-utils", "unrelated"})
+	test.Validate(result)
+
+	if err = ioutil.WriteFile(filename, []byte("go 1.18\n"), 0644); err != nil {
+		context.Fatal(err)
+	}
+
+	test = simply.Target(writeWork(options) != nil, context, "An existing go.work should not be replaced without -force")
+	result = test.Equals(true)
+	test.Validate(result)
+
+	data, _ := ioutil.ReadFile(filename)
+	test = simply.Target(string(data), context, "An existing go.work should be left untouched")
+	result = test.Equals("go 1.18\n")
+	test.Validate(result)
+
+	forceWork = true
+	defer func() { forceWork = false }()
+
+	test = simply.Target(writeWork(options), context, "-force should replace an existing go.work")
+	result = test.Assert().Equals(nil)
+	test.Validate(result)
+}